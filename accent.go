@@ -0,0 +1,125 @@
+package aozoraconv
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// accentMarks is the Aozora Bunko accent decomposition table;
+// mark after a base letter stands for the combining character
+var accentMarks = []struct {
+	mark  string
+	comb  rune
+	bases string
+}{
+	{"`", 0x0300, "aeiouAEIOU"},   // grave
+	{"'", 0x0301, "aeiouyAEIOUY"}, // acute
+	{"^", 0x0302, "aeiouAEIOU"},   // circumflex
+	{"~", 0x0303, "anoANO"},       // tilde
+	{":", 0x0308, "aeiouyAEIOUY"}, // diaeresis
+	{"&", 0x030A, "aA"},           // ring above
+	{",", 0x0327, "cC"},           // cedilla
+	{"_", 0x0304, "aeiouAEIOU"},   // macron
+}
+
+// accentSpecials are letters which are not composed from a base letter
+var accentSpecials = []string{
+	"ae&", "æ", "AE&", "Æ",
+	"oe&", "œ", "OE&", "Œ",
+	"s&", "ß",
+	"o/", "ø", "O/", "Ø",
+	"!@", "¡", "?@", "¿",
+}
+
+var (
+	accentExpand    = map[string]rune{}
+	accentDecompose = map[rune]string{}
+	accentSpanRe    = regexp.MustCompile("〔([\x20-\x7e]+)〕")
+	accentRunRe     *regexp.Regexp
+)
+
+func init() {
+	for _, m := range accentMarks {
+		for _, b := range m.bases {
+			r, _ := utf8.DecodeRuneInString(norm.NFC.String(string([]rune{b, m.comb})))
+			accentExpand[string(b)+m.mark] = r
+			accentDecompose[r] = string(b) + m.mark
+		}
+	}
+	for i := 0; i < len(accentSpecials); i += 2 {
+		r, _ := utf8.DecodeRuneInString(accentSpecials[i+1])
+		accentExpand[accentSpecials[i]] = r
+		accentDecompose[r] = accentSpecials[i]
+	}
+
+	runes := make([]string, 0, len(accentDecompose))
+	for r := range accentDecompose {
+		if r != '¡' && r != '¿' {
+			runes = append(runes, string(r))
+		}
+	}
+	sort.Strings(runes)
+	// words are not joined across apostrophes, which would be read as acute accents in the span
+	word := "[A-Za-z" + strings.Join(runes, "") + "]+"
+	accentRunRe = regexp.MustCompile("[¡¿]?" + word + "(?:[ \\-]" + word + ")*")
+}
+
+// ExpandAccent converts accent decomposition notation such as `〔cafe'〕`
+// into Unicode Latin letters (`café`). Brackets without any accent notation
+// are left as is.
+func ExpandAccent(str string) string {
+	return accentSpanRe.ReplaceAllStringFunc(str, func(span string) string {
+		body := accentSpanRe.FindStringSubmatch(span)[1]
+		var b strings.Builder
+		expanded := false
+		for i := 0; i < len(body); {
+			n := 0
+			for _, l := range []int{3, 2} {
+				if i+l > len(body) {
+					continue
+				}
+				if r, ok := accentExpand[body[i:i+l]]; ok {
+					b.WriteRune(r)
+					n = l
+					break
+				}
+			}
+			if n == 0 {
+				b.WriteByte(body[i])
+				n = 1
+			} else {
+				expanded = true
+			}
+			i += n
+		}
+		if !expanded {
+			return span
+		}
+		return b.String()
+	})
+}
+
+// DecomposeAccent converts runs of accented Latin letters such as `café`
+// into accent decomposition notation in brackets (`〔cafe'〕`).
+func DecomposeAccent(str string) string {
+	return accentRunRe.ReplaceAllStringFunc(str, func(run string) string {
+		var b strings.Builder
+		decomposed := false
+		for _, r := range run {
+			if s, ok := accentDecompose[r]; ok {
+				b.WriteString(s)
+				decomposed = true
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		if !decomposed {
+			return run
+		}
+		return "〔" + b.String() + "〕"
+	})
+}
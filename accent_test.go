@@ -0,0 +1,83 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExpandAccent(t *testing.T) {
+	var convertedPairs = []struct {
+		in  string
+		out string
+	}{
+		{"〔cafe'〕", "café"},
+		{"〔Mu:nchen〕", "München"},
+		{"〔ae&〕", "æ"},
+		{"〔OE&uvre〕", "Œuvre"},
+		{"〔Stras&e〕", "Straße"},
+		{"〔A&ngstro:m〕", "Ångström"},
+		{"〔Franc,ois〕", "François"},
+		{"〔Sa~o Paulo〕", "São Paulo"},
+		{"〔Ko/benhavn〕", "København"},
+		{"〔!@Hola!〕", "¡Hola!"},
+		{"〔To_kyo_〕", "Tōkyō"},
+		{"〔注〕", "〔注〕"},
+		{"〔abc〕", "〔abc〕"},
+		{"彼は〔cafe'〕で", "彼はcaféで"},
+	}
+	for _, tt := range convertedPairs {
+		if got := ExpandAccent(tt.in); got != tt.out {
+			t.Errorf("ExpandAccent got: %v want: %v", got, tt.out)
+		}
+	}
+}
+
+func TestDecomposeAccent(t *testing.T) {
+	var convertedPairs = []struct {
+		in  string
+		out string
+	}{
+		{"café", "〔cafe'〕"},
+		{"彼はcaféで", "彼は〔cafe'〕で"},
+		{"São Paulo", "〔Sa~o Paulo〕"},
+		{"¡Olé!", "〔!@Ole'〕!"},
+		{"abc", "abc"},
+		{"Straße und München", "〔Stras&e und Mu:nchen〕"},
+		{"I'm at the café", "I'〔m at the cafe'〕"},
+		{"Hugo's café", "Hugo'〔s cafe'〕"},
+		{"the café's menu", "〔the cafe'〕's menu"},
+		{"Hôtel-Dieu", "〔Ho^tel-Dieu〕"},
+	}
+	for _, tt := range convertedPairs {
+		if got := DecomposeAccent(tt.in); got != tt.out {
+			t.Errorf("DecomposeAccent got: %v want: %v", got, tt.out)
+		}
+		if got := ExpandAccent(DecomposeAccent(tt.in)); got != tt.in {
+			t.Errorf("ExpandAccent(DecomposeAccent) got: %v want: %v", got, tt.in)
+		}
+	}
+}
+
+func TestEncodeWithAccent(t *testing.T) {
+	output := new(bytes.Buffer)
+	if err := Encode(strings.NewReader("カフェはcafé"), output); err == nil {
+		t.Errorf("Encode should be error without WithAccent")
+	}
+
+	output.Reset()
+	if err := Encode(strings.NewReader("カフェはcafé"), output, WithAccent()); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("カフェは〔cafe'〕"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+
+	decoded := new(bytes.Buffer)
+	if err := Decode(output, decoded, WithAccent()); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got, want := decoded.String(), "カフェはcafé"; got != want {
+		t.Errorf("Decode got: %v want: %v", got, want)
+	}
+}
@@ -1,10 +1,15 @@
 package aozoraconv
 
+//go:generate go run maketables.go -src jisx0213-2004-std.txt -o tables.go
+
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
@@ -53,26 +58,174 @@ func ConvRev(str string) string {
 	return aozoraUtf8CharReplacerR.Replace(str)
 }
 
+// Option is an optional conversion for Encode and Decode
+type Option func(*config)
+
+type config struct {
+	upgradeGaiji bool
+	gaijiReport  *[]GaijiChange
+	accent       bool
+	kunoji       bool
+	normRules    NormRule
+	normSummary  NormalizeSummary
+	fallback     bool
+	jitai        Jitai
+	dialect      Dialect
+	eol          LineEnding
+	bom          BOMMode
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithUpgradeGaiji rewrites Unicode gaiji annotations into JIS X 0213 references
+// (see UpgradeGaiji). Changed annotations are appended to report unless it is nil.
+func WithUpgradeGaiji(report *[]GaijiChange) Option {
+	return func(c *config) {
+		c.upgradeGaiji = true
+		c.gaijiReport = report
+	}
+}
+
+// WithAccent converts accent decomposition notation (`〔cafe'〕`) into
+// Unicode Latin letters on Decode, and vice versa on Encode.
+func WithAccent() Option {
+	return func(c *config) {
+		c.accent = true
+	}
+}
+
+// WithKunoji converts kunoji-ten in Aozora Bunko notation (`／＼`, `／″＼`)
+// into Unicode (`〳〵`, `〴〵`) on Decode, and vice versa on Encode.
+func WithKunoji() Option {
+	return func(c *config) {
+		c.kunoji = true
+	}
+}
+
+// WithNormalize converts characters prohibited in Aozora Bunko format on Encode
+// (see Normalize). Replacements are counted in summary unless it is nil.
+func WithNormalize(rules NormRule, summary NormalizeSummary) Option {
+	return func(c *config) {
+		c.normRules = rules
+		c.normSummary = summary
+	}
+}
+
+// WithFallback replaces characters not in JIS X 0208 with fallback characters
+// and gaiji annotations on Encode (see Fallback)
+func WithFallback() Option {
+	return func(c *config) {
+		c.fallback = true
+	}
+}
+
+// WithJitai converts kanji into shinjitai or kyujitai on Decode and Encode.
+// On Encode, converted characters not in JIS X 0208 are written as gaiji annotations.
+func WithJitai(j Jitai) Option {
+	return func(c *config) {
+		c.jitai = j
+	}
+}
+
+// WithDialect rewrites web-novel notations of the dialect into Aozora Bunko format
+// before Encode (see ImportWebNovel)
+func WithDialect(d Dialect) Option {
+	return func(c *config) {
+		c.dialect = d
+	}
+}
+
+// WithLineEnding converts line endings into CRLF or LF
+func WithLineEnding(eol LineEnding) Option {
+	return func(c *config) {
+		c.eol = eol
+	}
+}
+
+// WithBOM strips or adds BOM. BOMAdd is available only on Decode
+// because Shift_JIS has no BOM.
+func WithBOM(mode BOMMode) Option {
+	return func(c *config) {
+		c.bom = mode
+	}
+}
+
+// decodeFilter applies optional conversions to decoded Unicode text
+func (c *config) decodeFilter(str string) string {
+	if c.accent {
+		str = ExpandAccent(str)
+	}
+	if c.kunoji {
+		str = ExpandKunoji(str)
+	}
+	str = c.upgrade(str)
+	str = convJitai(str, c.jitai, false)
+	return ConvBOM(ConvLineEnding(str, c.eol), c.bom)
+}
+
+// encodeFilter applies optional conversions to Unicode text before encoding
+func (c *config) encodeFilter(str string) string {
+	str = ConvBOM(ConvLineEnding(str, c.eol), c.bom)
+	str = ImportWebNovel(str, c.dialect)
+	if c.normRules != 0 {
+		str = Normalize(str, c.normRules, c.normSummary)
+	}
+	str = convJitai(str, c.jitai, true)
+	if c.fallback {
+		str = Fallback(str)
+	}
+	str = c.upgrade(str)
+	if c.accent {
+		str = DecomposeAccent(str)
+	}
+	if c.kunoji {
+		str = FoldKunoji(str)
+	}
+	return str
+}
+
+func (c *config) upgrade(str string) string {
+	if c.upgradeGaiji {
+		var changes []GaijiChange
+		str, changes = UpgradeGaiji(str)
+		if c.gaijiReport != nil {
+			*c.gaijiReport = append(*c.gaijiReport, changes...)
+		}
+	}
+	return str
+}
+
 // Decode convert from UTF-8 into Aozora Bunko format (Shift_JIS)
-func Decode(input io.Reader, output io.Writer) (err error) {
+func Decode(input io.Reader, output io.Writer, opts ...Option) (err error) {
+	c := newConfig(opts)
 	decoder := japanese.ShiftJIS.NewDecoder()
 	reader := transform.NewReader(input, decoder)
 	ret, err := ioutil.ReadAll(reader)
 	if err != nil {
 		return err
 	}
-	str := ConvRev(string(ret))
+	str := c.decodeFilter(ConvRev(string(ret)))
 	_, err = fmt.Fprint(output, str)
 	return err
 }
 
 // Encode convert from Aozora Bunko format (Shift_JIS) into UTF-8
-func Encode(input io.Reader, output io.Writer) (err error) {
+func Encode(input io.Reader, output io.Writer, opts ...Option) (err error) {
+	c := newConfig(opts)
+	if c.bom == BOMAdd {
+		return fmt.Errorf("BOM is not available in Shift_JIS")
+	}
 	ret, err := ioutil.ReadAll(input)
 	if err != nil {
 		return err
 	}
-	str := Conv(string(ret))
+	str := Conv(c.encodeFilter(string(ret)))
 	encoder := japanese.ShiftJIS.NewEncoder()
 	writer := transform.NewWriter(output, encoder)
 	_, err = fmt.Fprint(writer, str)
@@ -84,58 +237,78 @@ func Jis2Uni(men, ku, ten int) (str string, err error) {
 	if men < 1 || men > 2 || ku < 1 || ku > 94 || ten < 1 || ten > 94 {
 		return "", fmt.Errorf("error: args should be in 1..2, 1..94, 1..94")
 	}
-	chr := jis0213Decode[men-1][ku-1][ten-1]
+	chr := decodeJis(men, ku, ten)
 	if chr == "" {
 		return "", fmt.Errorf("invalid access men: %v ku:%v ten:%v", men, ku, ten)
 	}
 	return chr, nil
 }
 
-// Uni2Jis returns a pointer of JisEntry
-func Uni2Jis(str string) (jis JisEntry, err error) {
-	var s1 uint16
-	r := []rune(str)
-	r1 := r[0]
-	if len(r) == 1 {
-		switch {
-		case 0x20 <= r1 && r1 < 0x7f:
-			return JisEntry{0, 0, 0}, fmt.Errorf("ASCII character")
-		case encode0Low <= r1 && r1 < encode0High:
-			if s1 = encode0[r1-encode0Low]; (s1>>planeShift)&0x0003 > 0 {
-				goto write2
-			}
-		case encode1Low <= r1 && r1 < encode1High:
-			if s1 = encode1[r1-encode1Low]; (s1>>planeShift)&0x0003 > 0 {
-				goto write2
-			}
-		case encode2Low <= r1 && r1 < encode2High:
-			if s1 = encode2[r1-encode2Low]; (s1>>planeShift)&0x0003 > 0 {
-				goto write2
-			}
-		case encode3Low <= r1 && r1 < encode3High:
-			if s1 = encode3[r1-encode3Low]; (s1>>planeShift)&0x0003 > 0 {
-				goto write2
-			}
-		case encode4Low <= r1 && r1 < encode4High:
-			if s1 = encode4[r1-encode4Low]; (s1>>planeShift)&0x0003 > 0 {
-				goto write2
-			}
+// decodeJis returns a string of men-ku-ten, or "" if no character is assigned
+func decodeJis(men, ku, ten int) string {
+	v := jis0213Decode[((men-1)*94+ku-1)*94+ten-1]
+	off, n := v>>decodeLenBits, v&(1<<decodeLenBits-1)
+	return jis0213Chars[off : off+n]
+}
+
+// Uni2Jis returns JISCode of a character (1 or 2 runes) in JIS X 0213:2004.
+// Alternative mappings like U+FF5E for 1-1-33 are also accepted.
+func Uni2Jis(str string) (jis JISCode, err error) {
+	return JIS2004.Uni2Jis(str)
+}
+
+// errors of uni2jis are preallocated to make lookup fast
+var (
+	errASCII       = errors.New("ASCII character")
+	errInvalidChar = errors.New("invalid character")
+	errInvalidPair = errors.New("invalid character pair")
+	errLength      = errors.New("length of string should be 1 or 2")
+)
+
+func uni2jis(str string) (jis JISCode, err error) {
+	r1, n1 := utf8.DecodeRuneInString(str)
+	r2, n2 := utf8.DecodeRuneInString(str[n1:])
+	switch {
+	case n1 > 0 && n1 == len(str):
+		if 0x20 <= r1 && r1 < 0x7f {
+			return JisEntry{0, 0, 0}, errASCII
+		}
+		if jis, ok := encodeRune(r1); ok {
+			return jis, nil
 		}
-		return JisEntry{0, 0, 0}, fmt.Errorf("invalid character")
-	write2:
-		men := int8(s1 >> planeShift)
-		ku := int8((s1 >> codeShift) & codeMask)
-		ten := int8((s1) & codeMask)
-		return JisEntry{men: men, ku: ku, ten: ten}, nil
-	} else if len(r) == 2 {
-		r2 := r[1]
-		entry, ok := multichars[r1][r2]
+		return JisEntry{0, 0, 0}, errInvalidChar
+	case n1 > 0 && n1+n2 == len(str):
+		entry, ok := lookupPair(jis0213Pairs[:], r1, r2)
 		if !ok {
-			return JisEntry{0, 0, 0}, err
+			return JisEntry{0, 0, 0}, errInvalidPair
 		}
 		return entry, nil
 	}
-	return JisEntry{0, 0, 0}, fmt.Errorf("length of string should be 1 or 2")
+	return JisEntry{0, 0, 0}, errLength
+}
+
+// encodeRune looks up JIS code of r in the encoding trie
+func encodeRune(r rune) (JISCode, bool) {
+	if r < 0 || r >= encodeMaxRune {
+		return JISCode{}, false
+	}
+	v := jis0213Encode[int(jis0213EncodeIndex[r>>encodeBlockShift])<<encodeBlockShift|int(r&encodeBlockMask)]
+	if v == 0 {
+		return JISCode{}, false
+	}
+	return JISCode{men: int8(v >> planeShift), ku: int8((v >> codeShift) & codeMask), ten: int8(v & codeMask)}, true
+}
+
+// lookupPair finds r1 and r2 in pairs sorted by runes
+func lookupPair(pairs []jisPair, r1, r2 rune) (JISCode, bool) {
+	i := sort.Search(len(pairs), func(i int) bool {
+		p := pairs[i]
+		return p.r1 > r1 || (p.r1 == r1 && p.r2 >= r2)
+	})
+	if i < len(pairs) && pairs[i].r1 == r1 && pairs[i].r2 == r2 {
+		return pairs[i].code, true
+	}
+	return JISCode{}, false
 }
 
 // Is0208 checks triplet men-ku-ten is in JIS X 0208 or not
@@ -183,8 +356,12 @@ func Is0208(men, ku, ten int) bool {
 	}
 }
 
-// Kuten2Sjis returns SJIS byte strings (2 byte) from ku-ten code
+// Kuten2Sjis returns SJIS byte strings (2 byte) from ku-ten code in plane 1.
+// It returns nil if ku-ten is out of range; see also Kuten2Sjis2004.
 func Kuten2Sjis(ku, ten int) []byte {
+	if checkKuten(1, ku, ten) != nil {
+		return nil
+	}
 	var seq, c1, c2, s1, s2 int
 	seq = (ku-1)*94 + (ten - 1)
 	c1 = seq / 188
@@ -170,6 +170,10 @@ func TestUni2Jis(t *testing.T) {
 		{"◆", JisEntry{men: 1, ku: 2, ten: 1}, true},
 		{"A", JisEntry{0, 0, 0}, false},
 		{"☺", JisEntry{0, 0, 0}, false},
+		{"か゚", JisEntry{men: 1, ku: 4, ten: 87}, true},
+		{"あ゚", JisEntry{0, 0, 0}, false},
+		{"あいう", JisEntry{0, 0, 0}, false},
+		{"", JisEntry{0, 0, 0}, false},
 	}
 	for _, tt := range convertedPairs {
 		got, err := Uni2Jis(tt.in)
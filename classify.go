@@ -0,0 +1,88 @@
+package aozoraconv
+
+import (
+	"unicode/utf8"
+)
+
+// CharClass is the classification of a character in JIS X 0213
+type CharClass struct {
+	Char       string  // the character
+	Code       JISCode // JIS X 0213 code; zero value if not in JIS X 0213
+	Level      int     // JIS kanji level (1-4); 0 for non-kanji or characters not in JIS X 0213
+	Kanji      bool    // kanji or not
+	In0208     bool    // in JIS X 0208 or not
+	In0213     bool    // in JIS X 0213 or not
+	Added2004  bool    // one of 10 characters added in JIS X 0213:2004
+	NeedsGaiji bool    // needs gaiji annotation in Aozora Bunko format (Shift_JIS)
+}
+
+// Plane returns JIS X 0213 plane (1 or 2); 0 if not in JIS X 0213
+func (c CharClass) Plane() int {
+	return c.Code.Men()
+}
+
+// KanjiLevel returns JIS kanji level (第1〜第4水準) of men-ku-ten code,
+// or 0 for non-kanji
+func KanjiLevel(men, ku, ten int) int {
+	switch {
+	case men == 2:
+		return 4
+	case men != 1 || ku < 14:
+		return 0
+	case Is0208(men, ku, ten) && ku <= 47:
+		return 1
+	case Is0208(men, ku, ten) && ku >= 48:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Is2004 checks triplet men-ku-ten is one of 10 characters added in JIS X 0213:2004
+func Is2004(men, ku, ten int) bool {
+	for _, c := range jis0213Added2004 {
+		if c.Men() == men && c.Ku() == ku && c.Ten() == ten {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify returns the classification of r
+func Classify(r rune) CharClass {
+	return classify(string(r))
+}
+
+// ClassifyString returns the classifications of characters in str.
+// Combining sequences in JIS X 0213 (like `か` + U+309A) are treated as one character.
+func ClassifyString(str string) []CharClass {
+	var ret []CharClass
+	s := NewSegmenter(str)
+	for s.Next() {
+		text, _, _ := s.Segment()
+		ret = append(ret, classify(text))
+	}
+	return ret
+}
+
+func classify(chr string) CharClass {
+	c := CharClass{Char: chr}
+	if len(chr) == 1 && chr[0] < utf8.RuneSelf {
+		// ASCII is available in Shift_JIS as is
+		return c
+	}
+	jis, err := Uni2Jis(ConvRev(chr))
+	if err != nil || !jis.IsValid() {
+		c.NeedsGaiji = true
+		return c
+	}
+	men, ku, ten := jis.Men(), jis.Ku(), jis.Ten()
+	c.Code = jis
+	c.In0213 = true
+	c.In0208 = Is0208(men, ku, ten)
+	c.Level = KanjiLevel(men, ku, ten)
+	c.Kanji = c.Level > 0
+	c.Added2004 = Is2004(men, ku, ten)
+	c.NeedsGaiji = !c.In0208
+	return c
+}
@@ -0,0 +1,76 @@
+package aozoraconv
+
+import (
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	var convertedPairs = []struct {
+		in  rune
+		out CharClass
+	}{
+		{'A', CharClass{Char: "A"}},
+		{'あ', CharClass{Char: "あ", Code: JISCode{1, 4, 2}, In0208: true, In0213: true}},
+		{'亜', CharClass{Char: "亜", Code: JISCode{1, 16, 1}, Level: 1, Kanji: true, In0208: true, In0213: true}},
+		{'弌', CharClass{Char: "弌", Code: JISCode{1, 48, 1}, Level: 2, Kanji: true, In0208: true, In0213: true}},
+		{'俱', CharClass{Char: "俱", Code: JISCode{1, 14, 1}, Level: 3, Kanji: true, In0213: true, Added2004: true, NeedsGaiji: true}},
+		{'𠮟', CharClass{Char: "𠮟", Code: JISCode{1, 47, 52}, Level: 3, Kanji: true, In0213: true, Added2004: true, NeedsGaiji: true}},
+		{'𠂉', CharClass{Char: "𠂉", Code: JISCode{2, 1, 1}, Level: 4, Kanji: true, In0213: true, NeedsGaiji: true}},
+		{'①', CharClass{Char: "①", Code: JISCode{1, 13, 1}, In0213: true, NeedsGaiji: true}},
+		{'〜', CharClass{Char: "〜", Code: JISCode{1, 1, 33}, In0208: true, In0213: true}},
+		{'～', CharClass{Char: "～", Code: JISCode{1, 1, 33}, In0208: true, In0213: true}},
+		{'☺', CharClass{Char: "☺", NeedsGaiji: true}},
+	}
+	for _, tt := range convertedPairs {
+		if got := Classify(tt.in); got != tt.out {
+			t.Errorf("Classify(%c) got: %+v want: %+v", tt.in, got, tt.out)
+		}
+	}
+	if got := Classify('𠂉').Plane(); got != 2 {
+		t.Errorf("CharClass.Plane got: %v want: 2", got)
+	}
+	if got := Classify('☺').Plane(); got != 0 {
+		t.Errorf("CharClass.Plane got: %v want: 0", got)
+	}
+}
+
+func TestClassifyString(t *testing.T) {
+	got := ClassifyString("か゚亜𠂉")
+	if len(got) != 3 {
+		t.Fatalf("ClassifyString got %v characters want 3", len(got))
+	}
+	if got[0].Char != "か゚" || got[0].Code != (JISCode{1, 4, 87}) || !got[0].NeedsGaiji {
+		t.Errorf("ClassifyString got: %+v", got[0])
+	}
+	if got[1].Level != 1 || got[2].Level != 4 {
+		t.Errorf("ClassifyString got levels: %v, %v", got[1].Level, got[2].Level)
+	}
+}
+
+func TestKanjiLevel(t *testing.T) {
+	var convertedPairs = []struct {
+		men, ku, ten int
+		level        int
+		is2004       bool
+	}{
+		{1, 1, 1, 0, false},
+		{1, 13, 1, 0, false},
+		{1, 14, 1, 3, true},
+		{1, 16, 1, 1, false},
+		{1, 47, 51, 1, false},
+		{1, 47, 52, 3, true},
+		{1, 48, 1, 2, false},
+		{1, 84, 6, 2, false},
+		{1, 84, 7, 3, true},
+		{1, 94, 94, 3, true},
+		{2, 1, 1, 4, false},
+	}
+	for _, tt := range convertedPairs {
+		if got := KanjiLevel(tt.men, tt.ku, tt.ten); got != tt.level {
+			t.Errorf("KanjiLevel(%v, %v, %v) got: %v want: %v", tt.men, tt.ku, tt.ten, got, tt.level)
+		}
+		if got := Is2004(tt.men, tt.ku, tt.ten); got != tt.is2004 {
+			t.Errorf("Is2004(%v, %v, %v) got: %v want: %v", tt.men, tt.ku, tt.ten, got, tt.is2004)
+		}
+	}
+}
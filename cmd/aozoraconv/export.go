@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/takahashim/aozoraconv"
+)
+
+// exportOptions are options of output formats
+type exportOptions struct {
+	latexUTF   bool
+	mdRuby     string
+	mdEmphasis string
+	ssmlMax    int
+	outpath    string // SSML chunks are written to files numbered after it
+}
+
+// markdownOptions parses options of Markdown
+func (e exportOptions) markdownOptions() (aozoraconv.MarkdownOptions, error) {
+	var opts aozoraconv.MarkdownOptions
+	switch strings.ToLower(e.mdRuby) {
+	case "", "html":
+		opts.Ruby = aozoraconv.MarkdownRubyHTML
+	case "brace":
+		opts.Ruby = aozoraconv.MarkdownRubyBrace
+	default:
+		return opts, fmt.Errorf("unknown ruby notation: %s", e.mdRuby)
+	}
+	switch strings.ToLower(e.mdEmphasis) {
+	case "", "strong":
+		opts.Emphasis = aozoraconv.MarkdownEmphasisStrong
+	case "html":
+		opts.Emphasis = aozoraconv.MarkdownEmphasisHTML
+	default:
+		return opts, fmt.Errorf("unknown emphasis notation: %s", e.mdEmphasis)
+	}
+	return opts, nil
+}
+
+// sourceText reads input as Aozora Bunko format (Unicode): imported from the format from,
+// decoded from Shift_JIS (EncUtf8) or read as UTF-8 (EncSjis)
+func sourceText(input io.Reader, from string, enc int, opts []aozoraconv.Option) (string, error) {
+	if from != "" {
+		return importText(input, from)
+	}
+	if enc == aozoraconv.EncUtf8 {
+		buf := new(bytes.Buffer)
+		err := aozoraconv.Decode(input, buf, opts...)
+		return buf.String(), err
+	}
+	ret, err := ioutil.ReadAll(input)
+	return string(ret), err
+}
+
+// doExport writes input in the format (UTF-8 for text formats)
+func doExport(input io.Reader, output io.Writer, format, from string, enc int, opts []aozoraconv.Option, eopts exportOptions) error {
+	text, err := sourceText(input, from, enc, opts)
+	if err != nil {
+		return err
+	}
+	doc := aozoraconv.ParseDocument(text)
+	switch strings.ToLower(format) {
+	case "latex", "tex":
+		return aozoraconv.WriteLaTeX(output, doc, aozoraconv.LaTeXOptions{UTF: eopts.latexUTF})
+	case "markdown", "md":
+		mopts, err := eopts.markdownOptions()
+		if err != nil {
+			return err
+		}
+		return aozoraconv.WriteMarkdown(output, doc, mopts)
+	case "tei":
+		return aozoraconv.WriteTEI(output, doc)
+	case "docx":
+		return aozoraconv.WriteDOCX(output, doc)
+	case "json":
+		return aozoraconv.WriteJSON(output, doc)
+	case "ssml":
+		return writeSSML(doc, eopts)
+	}
+	return fmt.Errorf("unknown output format: %s", format)
+}
+
+// writeSSML writes SSML into eopts.outpath (standard output if empty). If it is
+// split into chunks, each chunk is written to a file numbered like out-1.ssml.
+func writeSSML(doc *aozoraconv.Document, eopts exportOptions) error {
+	if eopts.ssmlMax < 0 {
+		return fmt.Errorf("invalid maximum bytes of SSML: %d", eopts.ssmlMax)
+	}
+	chunks, err := aozoraconv.SSML(doc, aozoraconv.SSMLOptions{MaxBytes: eopts.ssmlMax})
+	if err != nil {
+		return err
+	}
+	if len(chunks) <= 1 {
+		output, err := getOuput(eopts.outpath)
+		if err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			if _, err := io.WriteString(output, chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if eopts.outpath == "" {
+		return fmt.Errorf("SSML is split into %d chunks; set -o to write them into numbered files", len(chunks))
+	}
+	ext := filepath.Ext(eopts.outpath)
+	width := len(strconv.Itoa(len(chunks)))
+	for i, chunk := range chunks {
+		name := fmt.Sprintf("%s-%0*d%s", strings.TrimSuffix(eopts.outpath, ext), width, i+1, ext)
+		if err := ioutil.WriteFile(name, []byte(chunk), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
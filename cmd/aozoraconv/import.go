@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/takahashim/aozoraconv"
+)
+
+// importText converts input in the format from into Aozora Bunko format (Unicode)
+func importText(input io.Reader, from string) (string, error) {
+	switch strings.ToLower(from) {
+	case "xhtml", "html":
+		return aozoraconv.ImportXHTML(input)
+	case "json":
+		doc, err := aozoraconv.ReadJSON(input)
+		if err != nil {
+			return "", err
+		}
+		return aozoraconv.FormatDocument(doc)
+	case "kakuyomu", "narou", "pixiv":
+		d, err := aozoraconv.ParseDialect(from)
+		if err != nil {
+			return "", err
+		}
+		text, err := ioutil.ReadAll(input)
+		if err != nil {
+			return "", err
+		}
+		return aozoraconv.ImportWebNovel(string(text), d), nil
+	}
+	return "", fmt.Errorf("unknown import format: %s", from)
+}
+
+// doImport imports input and writes it in Shift_JIS (EncSjis) or UTF-8 (EncUtf8).
+// Line endings and BOM of UTF-8 are converted into eol and bom; opts convert them on Encode.
+func doImport(input io.Reader, output io.Writer, from string, enc int, opts []aozoraconv.Option, eol aozoraconv.LineEnding, bom aozoraconv.BOMMode) error {
+	text, err := importText(input, from)
+	if err != nil {
+		return err
+	}
+	if enc == aozoraconv.EncUtf8 {
+		_, err = io.WriteString(output, aozoraconv.ConvBOM(aozoraconv.ConvLineEnding(text, eol), bom))
+		return err
+	}
+	return aozoraconv.Encode(strings.NewReader(text), output, opts...)
+}
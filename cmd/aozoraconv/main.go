@@ -20,7 +20,7 @@ func getOuput(path string) (output io.Writer, err error) {
 	if path == "" {
 		return os.Stdout, nil
 	}
-	output, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	output, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +41,40 @@ func getInput(path string, stdin bool) (input io.Reader, err error) {
 	return input, nil
 }
 
+// doCheck reports problems against Aozora Bunko submission rules
+func doCheck(input io.Reader, enc int) int {
+	text, err := readText(input, enc == aozoraconv.EncUtf8)
+	if err != nil {
+		errorf("error: %v", err)
+		return 1
+	}
+	issues := aozoraconv.CheckText(text)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// doCheckRuby reports conflicting readings and mis-detected bases of ruby
+func doCheckRuby(input io.Reader, enc int) int {
+	text, err := readText(input, enc == aozoraconv.EncUtf8)
+	if err != nil {
+		errorf("error: %v", err)
+		return 1
+	}
+	issues := aozoraconv.CheckRuby(text)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
+
 func doMain() int {
 
 	var (
@@ -49,6 +83,18 @@ func doMain() int {
 		enc              int
 		path, outpath    string
 		encoding         string
+		upgradeGaiji     bool
+		accent           bool
+		kunoji           bool
+		normalize        string
+		fallback         bool
+		jitai            string
+		from             string
+		format           string
+		eopts            exportOptions
+		eol, bom         string
+		check            bool
+		checkRuby        bool
 	)
 
 	flag.StringVar(&encoding, "e", "sjis", "set output encoding (sjis or utf8)")
@@ -56,6 +102,22 @@ func doMain() int {
 	flag.BoolVar(&useUtf8, "u", false, "convert from Shift_JIS into UTF-8")
 	flag.StringVar(&outpath, "o", "", "output filename")
 	flag.BoolVar(&useStdin, "stdin", false, "use standard input")
+	flag.BoolVar(&accent, "accent", false, "convert accent decomposition notation into Unicode Latin letters and back")
+	flag.BoolVar(&kunoji, "kunoji", false, "convert kunoji-ten notation into Unicode and back")
+	flag.StringVar(&normalize, "normalize", "", "normalize prohibited characters on encoding (all or kana,mark,digit,enclosed)")
+	flag.BoolVar(&fallback, "fallback", false, "replace variant characters not in JIS X 0208 with fallback characters and gaiji annotations on encoding")
+	flag.StringVar(&jitai, "jitai", "preserve", "convert kanji into shinjitai or kyujitai (shin, kyu or preserve)")
+	flag.StringVar(&eol, "eol", "preserve", "convert line endings (crlf, lf or preserve)")
+	flag.StringVar(&bom, "bom", "preserve", "strip or add BOM (strip, add or preserve)")
+	flag.BoolVar(&check, "check", false, "check BOM, line endings and trailing whitespace instead of converting")
+	flag.BoolVar(&checkRuby, "check-ruby", false, "check conflicting readings and base spans of ruby instead of converting")
+	flag.StringVar(&from, "from", "", "import from other format into Aozora Bunko format (xhtml, json, kakuyomu, narou or pixiv)")
+	flag.StringVar(&format, "f", "aozora", "output format (aozora, latex, markdown, tei, docx, ssml or json); text formats other than aozora are written in UTF-8")
+	flag.BoolVar(&eopts.latexUTF, "latex-utf", false, "write gaiji not in JIS X 0208 as \\UTF{} of otf package in LaTeX")
+	flag.StringVar(&eopts.mdRuby, "md-ruby", "html", "notation of ruby in Markdown (html or brace)")
+	flag.StringVar(&eopts.mdEmphasis, "md-emphasis", "strong", "notation of emphasis in Markdown (strong or html)")
+	flag.IntVar(&eopts.ssmlMax, "ssml-max", 5000, "maximum bytes of a SSML chunk; chunks are written to files numbered after -o like out-1.ssml (0 is no limit)")
+	flag.BoolVar(&upgradeGaiji, "upgrade-gaiji", false, "rewrite Unicode gaiji annotations into JIS X 0213 references")
 
 	flag.Parse()
 
@@ -67,12 +129,6 @@ func doMain() int {
 		return 1
 	}
 
-	output, err := getOuput(outpath)
-	if err != nil {
-		errorf("error: %s", err)
-		return 1
-	}
-
 	if strings.ToLower(encoding) == "utf8" || strings.ToLower(encoding) == "utf-8" || useUtf8 {
 		enc = aozoraconv.EncUtf8
 	} else if strings.ToLower(encoding) == "sjis" || strings.ToLower(encoding) == "shift_jis" || useSjis {
@@ -82,18 +138,104 @@ func doMain() int {
 		return 1
 	}
 
-	if enc == aozoraconv.EncUtf8 {
-		err = aozoraconv.Decode(input, output)
+	if check {
+		return doCheck(input, enc)
+	}
+	if checkRuby {
+		return doCheckRuby(input, enc)
+	}
+
+	// SSML may be split into numbered files, which writeSSML opens
+	output := io.Writer(os.Stdout)
+	if strings.ToLower(format) != "ssml" {
+		if output, err = getOuput(outpath); err != nil {
+			errorf("error: %s", err)
+			return 1
+		}
+	}
+	eopts.outpath = outpath
+
+	var opts []aozoraconv.Option
+	var gaijiChanges []aozoraconv.GaijiChange
+	if upgradeGaiji {
+		opts = append(opts, aozoraconv.WithUpgradeGaiji(&gaijiChanges))
+	}
+	if accent {
+		opts = append(opts, aozoraconv.WithAccent())
+	}
+	if kunoji {
+		opts = append(opts, aozoraconv.WithKunoji())
+	}
+	summary := aozoraconv.NormalizeSummary{}
+	if normalize != "" {
+		rules, err := aozoraconv.ParseNormRule(normalize)
+		if err != nil {
+			errorf("error: %v", err)
+			return 1
+		}
+		opts = append(opts, aozoraconv.WithNormalize(rules, summary))
+	}
+	if fallback {
+		opts = append(opts, aozoraconv.WithFallback())
+	}
+	j, err := aozoraconv.ParseJitai(jitai)
+	if err != nil {
+		errorf("error: %v", err)
+		return 1
+	}
+	opts = append(opts, aozoraconv.WithJitai(j))
+	var eolMode aozoraconv.LineEnding
+	switch strings.ToLower(eol) {
+	case "crlf":
+		eolMode = aozoraconv.EOLCRLF
+	case "lf":
+		eolMode = aozoraconv.EOLLF
+	case "preserve":
+	default:
+		errorf("error: unknown line ending: %s", eol)
+		return 1
+	}
+	var bomMode aozoraconv.BOMMode
+	switch strings.ToLower(bom) {
+	case "strip":
+		bomMode = aozoraconv.BOMStrip
+	case "add":
+		bomMode = aozoraconv.BOMAdd
+	case "preserve":
+	default:
+		errorf("error: unknown BOM mode: %s", bom)
+		return 1
+	}
+	opts = append(opts, aozoraconv.WithLineEnding(eolMode), aozoraconv.WithBOM(bomMode))
+
+	if f := strings.ToLower(format); f != "" && f != "aozora" {
+		err = doExport(input, output, format, from, enc, opts, eopts)
+	} else if from != "" {
+		err = doImport(input, output, from, enc, opts, eolMode, bomMode)
+	} else if enc == aozoraconv.EncUtf8 {
+		err = aozoraconv.Decode(input, output, opts...)
 	} else { // enc == aozoraconv.EncSjis
-		err = aozoraconv.Encode(input, output)
+		err = aozoraconv.Encode(input, output, opts...)
 	}
 	if err != nil {
 		errorf("error: %v", err)
 		return 1
 	}
+	for _, c := range gaijiChanges {
+		errorf("gaiji: %v", c)
+	}
+	for _, r := range summary.Replacements() {
+		errorf("normalize: %v: %s -> %s (%d)", r.Rule, r.From, r.To, summary[r])
+	}
 	return 0
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		os.Exit(doStats(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ruby-dict" {
+		os.Exit(doRubyDict(os.Args[2:]))
+	}
 	os.Exit(doMain())
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/takahashim/aozoraconv"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func writeRubyTSV(w io.Writer, entries []aozoraconv.RubyEntry) error {
+	var b strings.Builder
+	b.WriteString("base\treading\tcount\tsources\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s\t%s\t%d\t%s\n", e.Base, e.Reading, e.Count, strings.Join(e.Sources, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRubyMozc writes entries in the user dictionary format of Mozc (Google Japanese Input):
+// reading, word, part of speech and comment separated by tabs
+func writeRubyMozc(w io.Writer, entries []aozoraconv.RubyEntry) error {
+	var b strings.Builder
+	for _, e := range entries {
+		if aozoraconv.IsHiragana(e.Reading) {
+			fmt.Fprintf(&b, "%s\t%s\t名詞\t%d\n", e.Reading, e.Base, e.Count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRubyMSIME writes entries in the text format of Microsoft IME dictionary tool
+// (UTF-16LE with BOM and CRLF)
+func writeRubyMSIME(w io.Writer, entries []aozoraconv.RubyEntry) error {
+	var b strings.Builder
+	b.WriteString("!Microsoft IME Dictionary Tool\r\n!Format:WORDLIST\r\n!User Dictionary Name:aozoraconv\r\n\r\n")
+	for _, e := range entries {
+		if aozoraconv.IsHiragana(e.Reading) {
+			fmt.Fprintf(&b, "%s\t%s\t名詞\r\n", e.Reading, e.Base)
+		}
+	}
+	text, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(b.String())
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, text)
+	return err
+}
+
+// doRubyDict extracts ruby pairs from works
+func doRubyDict(args []string) int {
+	var (
+		format  string
+		useSjis bool
+		outpath string
+	)
+	fs := flag.NewFlagSet("ruby-dict", flag.ExitOnError)
+	fs.StringVar(&format, "f", "tsv", "output format (tsv, mozc or msime)")
+	fs.BoolVar(&useSjis, "s", false, "input files are Shift_JIS")
+	fs.StringVar(&outpath, "o", "", "output filename")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		errorf("error: input file is not defined")
+		return 1
+	}
+	dict := aozoraconv.NewRubyDict()
+	for _, path := range fs.Args() {
+		input, err := os.Open(path)
+		if err != nil {
+			errorf("error: %s", err)
+			return 1
+		}
+		text, err := readText(input, useSjis)
+		input.Close()
+		if err != nil {
+			errorf("error: %s: %s", path, err)
+			return 1
+		}
+		dict.Add(text, path)
+	}
+
+	output, err := getOuput(outpath)
+	if err != nil {
+		errorf("error: %s", err)
+		return 1
+	}
+	switch format {
+	case "tsv":
+		err = writeRubyTSV(output, dict.Entries())
+	case "mozc":
+		err = writeRubyMozc(output, dict.Entries())
+	case "msime":
+		err = writeRubyMSIME(output, dict.Entries())
+	default:
+		errorf("error: unknown format: %s", format)
+		return 1
+	}
+	if err != nil {
+		errorf("error: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/takahashim/aozoraconv"
+)
+
+// readText reads a work as Unicode text; Shift_JIS is decoded if sjis is true
+func readText(input io.Reader, sjis bool) (string, error) {
+	if sjis {
+		buf := new(bytes.Buffer)
+		err := aozoraconv.Decode(input, buf)
+		return buf.String(), err
+	}
+	ret, err := ioutil.ReadAll(input)
+	return string(ret), err
+}
+
+// statsRows returns rows of the summary table
+func statsRows(s *aozoraconv.Stats) [][]string {
+	return [][]string{
+		{"files", strconv.Itoa(s.Files)},
+		{"characters", strconv.Itoa(s.Characters)},
+		{"distinct", strconv.Itoa(s.Distinct)},
+		{"ascii", strconv.Itoa(s.ASCII)},
+		{"non_kanji", strconv.Itoa(s.NonKanji)},
+		{"level1", strconv.Itoa(s.Level1)},
+		{"level2", strconv.Itoa(s.Level2)},
+		{"level3", strconv.Itoa(s.Level3)},
+		{"level4", strconv.Itoa(s.Level4)},
+		{"unicode_only", strconv.Itoa(s.UnicodeOnly)},
+		{"unresolved", strconv.Itoa(s.Unresolved)},
+		{"gaiji", strconv.Itoa(len(s.Gaiji))},
+		{"ruby", strconv.Itoa(s.Ruby)},
+		{"ruby_density", strconv.FormatFloat(s.RubyDensity(), 'f', 2, 64)},
+		{"pages", strconv.Itoa(s.Pages)},
+	}
+}
+
+func writeStatsTable(w io.Writer, s *aozoraconv.Stats, freq bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	for _, row := range statsRows(s) {
+		fmt.Fprintf(tw, "%s\t%s\n", row[0], row[1])
+	}
+	for _, g := range s.Gaiji {
+		fmt.Fprintf(tw, "gaiji\t%s\n", g)
+	}
+	for _, c := range s.UnicodeOnlyChars {
+		fmt.Fprintf(tw, "unicode_only\t%s\t%U\n", c, []rune(c)[0])
+	}
+	if freq {
+		for _, f := range s.Frequency() {
+			fmt.Fprintf(tw, "freq\t%s\t%d\t%d\t%s\n", f.Char, f.Count, f.Level, f.Code)
+		}
+	}
+	return tw.Flush()
+}
+
+func writeStatsCSV(w io.Writer, s *aozoraconv.Stats, freq bool) error {
+	cw := csv.NewWriter(w)
+	if freq {
+		cw.Write([]string{"char", "count", "level", "code"})
+		for _, f := range s.Frequency() {
+			cw.Write([]string{f.Char, strconv.Itoa(f.Count), strconv.Itoa(f.Level), f.Code})
+		}
+	} else {
+		cw.Write([]string{"key", "value"})
+		cw.WriteAll(statsRows(s))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeStatsJSON(w io.Writer, s *aozoraconv.Stats, freq bool) error {
+	out := struct {
+		*aozoraconv.Stats
+		RubyDensity float64               `json:"ruby_density"`
+		Frequency   []aozoraconv.CharFreq `json:"frequency,omitempty"`
+	}{Stats: s, RubyDensity: s.RubyDensity()}
+	if freq {
+		out.Frequency = s.Frequency()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// doStats reports character statistics of works
+func doStats(args []string) int {
+	var (
+		format  string
+		useSjis bool
+		freq    bool
+	)
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.StringVar(&format, "f", "table", "output format (table, json or csv)")
+	fs.BoolVar(&useSjis, "s", false, "input files are Shift_JIS")
+	fs.BoolVar(&freq, "freq", false, "output frequency table of characters")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		errorf("error: input file is not defined")
+		return 1
+	}
+	stats := aozoraconv.NewStats()
+	for _, path := range fs.Args() {
+		input, err := os.Open(path)
+		if err != nil {
+			errorf("error: %s", err)
+			return 1
+		}
+		text, err := readText(input, useSjis)
+		input.Close()
+		if err != nil {
+			errorf("error: %s: %s", path, err)
+			return 1
+		}
+		stats.Add(text)
+	}
+
+	var err error
+	switch format {
+	case "table":
+		err = writeStatsTable(os.Stdout, stats, freq)
+	case "json":
+		err = writeStatsJSON(os.Stdout, stats, freq)
+	case "csv":
+		err = writeStatsCSV(os.Stdout, stats, freq)
+	default:
+		errorf("error: unknown format: %s", format)
+		return 1
+	}
+	if err != nil {
+		errorf("error: %v", err)
+		return 1
+	}
+	return 0
+}
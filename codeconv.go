@@ -0,0 +1,137 @@
+package aozoraconv
+
+import (
+	"fmt"
+)
+
+// Escape sequences of ISO-2022-JP-2004 to designate JIS X 0213 planes
+var (
+	Iso2022Plane1Escape = []byte("\x1b$(Q")
+	Iso2022Plane2Escape = []byte("\x1b$(P")
+)
+
+// checkKuten checks triplet men-ku-ten is in the range of JIS X 0213
+func checkKuten(men, ku, ten int) error {
+	if men < 1 || men > 2 || ku < 1 || ku > 94 || ten < 1 || ten > 94 {
+		return fmt.Errorf("invalid JIS code men: %v ku: %v ten: %v", men, ku, ten)
+	}
+	return nil
+}
+
+// Kuten2Sjis2004 returns Shift_JIS-2004 bytes (2 bytes) from men-ku-ten code.
+// Rows of plane 2 are available only in 1, 3-5, 8, 12-15 and 78-94.
+func Kuten2Sjis2004(men, ku, ten int) ([]byte, error) {
+	if err := checkKuten(men, ku, ten); err != nil {
+		return nil, err
+	}
+	var s1 int
+	switch {
+	case men == 1 && ku <= 62:
+		s1 = (ku + 257) / 2
+	case men == 1:
+		s1 = (ku + 385) / 2
+	case ku == 1, ku == 3, ku == 4, ku == 5, ku == 8, ku == 12, ku == 13, ku == 14, ku == 15:
+		s1 = (ku+0x1DF)/2 - (ku/8)*3
+	case ku >= 78:
+		s1 = (ku + 411) / 2
+	default:
+		return nil, fmt.Errorf("JIS code %d-%d-%d is not available in Shift_JIS-2004", men, ku, ten)
+	}
+	var s2 int
+	switch {
+	case ku%2 == 0:
+		s2 = ten + 158
+	case ten < 64:
+		s2 = ten + 63
+	default:
+		s2 = ten + 64
+	}
+	return []byte{byte(s1), byte(s2)}, nil
+}
+
+// sjisPlane2Rows maps lead bytes 0xF0..0xF4 into the odd and even rows of plane 2
+var sjisPlane2Rows = [5][2]int{{1, 8}, {3, 4}, {5, 12}, {13, 14}, {15, 78}}
+
+// Sjis2Kuten returns men-ku-ten code from Shift_JIS-2004 bytes (2 bytes)
+func Sjis2Kuten(b []byte) (men, ku, ten int, err error) {
+	if len(b) != 2 {
+		return 0, 0, 0, fmt.Errorf("Shift_JIS code should be 2 bytes: % X", b)
+	}
+	s1, s2 := b[0], b[1]
+	even := 0x9F <= s2 && s2 <= 0xFC
+	switch {
+	case 0x40 <= s2 && s2 <= 0x7E:
+		ten = int(s2) - 0x3F
+	case 0x80 <= s2 && s2 <= 0x9E:
+		ten = int(s2) - 0x40
+	case even:
+		ten = int(s2) - 0x9E
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid Shift_JIS trail byte: %02X", s2)
+	}
+	switch {
+	case 0x81 <= s1 && s1 <= 0x9F:
+		men, ku = 1, int(s1-0x81)*2+1
+	case 0xE0 <= s1 && s1 <= 0xEF:
+		men, ku = 1, int(s1-0xC1)*2+1
+	case 0xF0 <= s1 && s1 <= 0xF4:
+		rows := sjisPlane2Rows[s1-0xF0]
+		if even {
+			return 2, rows[1], ten, nil
+		}
+		return 2, rows[0], ten, nil
+	case 0xF5 <= s1 && s1 <= 0xFC:
+		men, ku = 2, int(s1-0xF5)*2+79
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid Shift_JIS lead byte: %02X", s1)
+	}
+	if even {
+		ku++
+	}
+	return men, ku, ten, nil
+}
+
+// Kuten2Euc returns EUC-JIS-2004 bytes from men-ku-ten code
+// (2 bytes, or 3 bytes with 0x8F prefix for plane 2)
+func Kuten2Euc(men, ku, ten int) ([]byte, error) {
+	if err := checkKuten(men, ku, ten); err != nil {
+		return nil, err
+	}
+	if men == 2 {
+		return []byte{0x8F, byte(ku + 0xA0), byte(ten + 0xA0)}, nil
+	}
+	return []byte{byte(ku + 0xA0), byte(ten + 0xA0)}, nil
+}
+
+// Euc2Kuten returns men-ku-ten code from EUC-JIS-2004 bytes
+func Euc2Kuten(b []byte) (men, ku, ten int, err error) {
+	men = 1
+	if len(b) == 3 && b[0] == 0x8F {
+		men, b = 2, b[1:]
+	}
+	if len(b) != 2 || b[0] < 0xA1 || b[0] > 0xFE || b[1] < 0xA1 || b[1] > 0xFE {
+		return 0, 0, 0, fmt.Errorf("invalid EUC-JIS-2004 code: % X", b)
+	}
+	return men, int(b[0]) - 0xA0, int(b[1]) - 0xA0, nil
+}
+
+// Kuten2Iso2022 returns ISO-2022-JP-2004 byte pair from men-ku-ten code.
+// The plane should be designated by Iso2022Plane1Escape or Iso2022Plane2Escape.
+func Kuten2Iso2022(men, ku, ten int) ([]byte, error) {
+	if err := checkKuten(men, ku, ten); err != nil {
+		return nil, err
+	}
+	return []byte{byte(ku + 0x20), byte(ten + 0x20)}, nil
+}
+
+// Iso2022ToKuten returns men-ku-ten code from ISO-2022-JP-2004 byte pair in plane men
+func Iso2022ToKuten(men int, b []byte) (ku, ten int, err error) {
+	if len(b) != 2 || b[0] < 0x21 || b[0] > 0x7E || b[1] < 0x21 || b[1] > 0x7E {
+		return 0, 0, fmt.Errorf("invalid ISO-2022-JP-2004 code: % X", b)
+	}
+	ku, ten = int(b[0])-0x20, int(b[1])-0x20
+	if err := checkKuten(men, ku, ten); err != nil {
+		return 0, 0, err
+	}
+	return ku, ten, nil
+}
@@ -0,0 +1,171 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKuten2Sjis2004(t *testing.T) {
+	var convertedPairs = []struct {
+		men, ku, ten int
+		sjis         []byte
+		isSuccess    bool
+	}{
+		{1, 1, 1, []byte{0x81, 0x40}, true},
+		{1, 1, 63, []byte{0x81, 0x7E}, true},
+		{1, 1, 64, []byte{0x81, 0x80}, true},
+		{1, 16, 1, []byte{0x88, 0x9F}, true},
+		{1, 62, 94, []byte{0x9F, 0xFC}, true},
+		{1, 63, 1, []byte{0xE0, 0x40}, true},
+		{1, 94, 94, []byte{0xEF, 0xFC}, true},
+		{2, 1, 1, []byte{0xF0, 0x40}, true},
+		{2, 8, 1, []byte{0xF0, 0x9F}, true},
+		{2, 3, 1, []byte{0xF1, 0x40}, true},
+		{2, 4, 1, []byte{0xF1, 0x9F}, true},
+		{2, 5, 1, []byte{0xF2, 0x40}, true},
+		{2, 12, 1, []byte{0xF2, 0x9F}, true},
+		{2, 13, 1, []byte{0xF3, 0x40}, true},
+		{2, 14, 1, []byte{0xF3, 0x9F}, true},
+		{2, 15, 1, []byte{0xF4, 0x40}, true},
+		{2, 78, 1, []byte{0xF4, 0x9F}, true},
+		{2, 79, 1, []byte{0xF5, 0x40}, true},
+		{2, 94, 94, []byte{0xFC, 0xFC}, true},
+		{2, 2, 1, nil, false},
+		{2, 77, 1, nil, false},
+		{0, 0, 0, nil, false},
+		{1, 0, 1, nil, false},
+		{1, 1, 95, nil, false},
+		{3, 1, 1, nil, false},
+	}
+	for _, tt := range convertedPairs {
+		got, err := Kuten2Sjis2004(tt.men, tt.ku, tt.ten)
+		if (err == nil) != tt.isSuccess {
+			t.Errorf("Kuten2Sjis2004(%v, %v, %v) error: %v", tt.men, tt.ku, tt.ten, err)
+		}
+		if !bytes.Equal(got, tt.sjis) {
+			t.Errorf("Kuten2Sjis2004(%v, %v, %v) got: % X want: % X", tt.men, tt.ku, tt.ten, got, tt.sjis)
+		}
+		if !tt.isSuccess {
+			continue
+		}
+		men, ku, ten, err := Sjis2Kuten(tt.sjis)
+		if err != nil || men != tt.men || ku != tt.ku || ten != tt.ten {
+			t.Errorf("Sjis2Kuten(% X) got: %v-%v-%v (%v)", tt.sjis, men, ku, ten, err)
+		}
+	}
+}
+
+func TestSjis2KutenError(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x81},
+		{0x81, 0x40, 0x40},
+		{0x80, 0x40},
+		{0xA0, 0x40},
+		{0xFD, 0x40},
+		{0x81, 0x3F},
+		{0x81, 0x7F},
+		{0x81, 0xFD},
+	} {
+		if _, _, _, err := Sjis2Kuten(b); err == nil {
+			t.Errorf("Sjis2Kuten(% X) should be error", b)
+		}
+	}
+}
+
+func TestKuten2Euc(t *testing.T) {
+	var convertedPairs = []struct {
+		men, ku, ten int
+		euc          []byte
+	}{
+		{1, 1, 1, []byte{0xA1, 0xA1}},
+		{1, 16, 1, []byte{0xB0, 0xA1}},
+		{1, 94, 94, []byte{0xFE, 0xFE}},
+		{2, 1, 1, []byte{0x8F, 0xA1, 0xA1}},
+		{2, 2, 1, []byte{0x8F, 0xA2, 0xA1}},
+	}
+	for _, tt := range convertedPairs {
+		got, err := Kuten2Euc(tt.men, tt.ku, tt.ten)
+		if err != nil || !bytes.Equal(got, tt.euc) {
+			t.Errorf("Kuten2Euc(%v, %v, %v) got: % X want: % X (%v)", tt.men, tt.ku, tt.ten, got, tt.euc, err)
+		}
+	}
+	if _, err := Kuten2Euc(1, 0, 0); err == nil {
+		t.Errorf("Kuten2Euc(1, 0, 0) should be error")
+	}
+	for _, b := range [][]byte{{0xA1}, {0xA0, 0xA1}, {0xA1, 0xFF}, {0x8E, 0xA1, 0xA1}, {0x8F, 0xA1}} {
+		if _, _, _, err := Euc2Kuten(b); err == nil {
+			t.Errorf("Euc2Kuten(% X) should be error", b)
+		}
+	}
+}
+
+func TestKuten2Iso2022(t *testing.T) {
+	got, err := Kuten2Iso2022(1, 16, 1)
+	if err != nil || !bytes.Equal(got, []byte{0x30, 0x21}) {
+		t.Errorf("Kuten2Iso2022 got: % X (%v)", got, err)
+	}
+	if _, err := Kuten2Iso2022(2, 95, 1); err == nil {
+		t.Errorf("Kuten2Iso2022(2, 95, 1) should be error")
+	}
+	for _, b := range [][]byte{{0x21}, {0x20, 0x21}, {0x21, 0x7F}} {
+		if _, _, err := Iso2022ToKuten(1, b); err == nil {
+			t.Errorf("Iso2022ToKuten(% X) should be error", b)
+		}
+	}
+	if _, _, err := Iso2022ToKuten(3, []byte{0x21, 0x21}); err == nil {
+		t.Errorf("Iso2022ToKuten(3) should be error")
+	}
+}
+
+func TestKuten2SjisInvalid(t *testing.T) {
+	for _, kt := range [][2]int{{0, 0}, {0, 1}, {1, 0}, {95, 1}, {1, 95}} {
+		if got := Kuten2Sjis(kt[0], kt[1]); got != nil {
+			t.Errorf("Kuten2Sjis(%v, %v) got: % X want: nil", kt[0], kt[1], got)
+		}
+	}
+}
+
+// TestAllCodeConv converts every cell of jis0213Decode and back
+func TestAllCodeConv(t *testing.T) {
+	sjisSeen := map[string]bool{}
+	eucSeen := map[string]bool{}
+	for men := 1; men <= 2; men++ {
+		for ku := 1; ku <= 94; ku++ {
+			for ten := 1; ten <= 94; ten++ {
+				if decodeJis(men, ku, ten) == "" {
+					continue
+				}
+
+				sjis, err := Kuten2Sjis2004(men, ku, ten)
+				if err != nil {
+					t.Errorf("Kuten2Sjis2004(%v, %v, %v) error: %v", men, ku, ten, err)
+				} else if m2, k2, t2, err := Sjis2Kuten(sjis); err != nil || m2 != men || k2 != ku || t2 != ten {
+					t.Errorf("Sjis2Kuten(% X) got: %v-%v-%v want: %v-%v-%v (%v)", sjis, m2, k2, t2, men, ku, ten, err)
+				} else if sjisSeen[string(sjis)] {
+					t.Errorf("Kuten2Sjis2004(%v, %v, %v) is duplicated: % X", men, ku, ten, sjis)
+				}
+				sjisSeen[string(sjis)] = true
+				if men == 1 && !bytes.Equal(sjis, Kuten2Sjis(ku, ten)) {
+					t.Errorf("Kuten2Sjis(%v, %v) got: % X want: % X", ku, ten, Kuten2Sjis(ku, ten), sjis)
+				}
+
+				euc, err := Kuten2Euc(men, ku, ten)
+				if err != nil {
+					t.Errorf("Kuten2Euc(%v, %v, %v) error: %v", men, ku, ten, err)
+				} else if m2, k2, t2, err := Euc2Kuten(euc); err != nil || m2 != men || k2 != ku || t2 != ten {
+					t.Errorf("Euc2Kuten(% X) got: %v-%v-%v want: %v-%v-%v (%v)", euc, m2, k2, t2, men, ku, ten, err)
+				} else if eucSeen[string(euc)] {
+					t.Errorf("Kuten2Euc(%v, %v, %v) is duplicated: % X", men, ku, ten, euc)
+				}
+				eucSeen[string(euc)] = true
+
+				iso, err := Kuten2Iso2022(men, ku, ten)
+				if err != nil {
+					t.Errorf("Kuten2Iso2022(%v, %v, %v) error: %v", men, ku, ten, err)
+				} else if k2, t2, err := Iso2022ToKuten(men, iso); err != nil || k2 != ku || t2 != ten {
+					t.Errorf("Iso2022ToKuten(% X) got: %v-%v want: %v-%v (%v)", iso, k2, t2, ku, ten, err)
+				}
+			}
+		}
+	}
+}
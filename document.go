@@ -0,0 +1,408 @@
+package aozoraconv
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BlockKind is a kind of Block
+type BlockKind int
+
+// Kinds of block
+const (
+	// BlockParagraph is a line of text
+	BlockParagraph BlockKind = iota
+	// BlockHeading is a heading; Level is 1 (大見出し), 2 (中見出し) or 3 (小見出し)
+	BlockHeading
+	// BlockPageBreak is 改ページ
+	BlockPageBreak
+	// BlockIndent is 字下げ of Blocks; Level is the width in characters
+	BlockIndent
+	// BlockBottom is 地付き (Level is 0) or 地からN字上げ (Level is N) of Blocks
+	BlockBottom
+)
+
+// InlineKind is a kind of Inline
+type InlineKind int
+
+// Kinds of inline
+const (
+	// InlineText is plain text
+	InlineText InlineKind = iota
+	// InlineRuby is Text with ruby reading Ruby
+	InlineRuby
+	// InlineEmphasis is Children with 傍点 or 傍線; Style is the name like "傍点"
+	InlineEmphasis
+	// InlineTateChuYoko is Children in 縦中横
+	InlineTateChuYoko
+	// InlineGaiji is a gaiji annotation; Text is the character if known
+	InlineGaiji
+	// InlineNote is an annotation not interpreted
+	InlineNote
+)
+
+// Document is a parsed work in Aozora Bunko format
+type Document struct {
+	Title  string
+	Author string
+	Blocks []Block
+}
+
+// Block is a block of Document
+type Block struct {
+	Kind    BlockKind
+	Level   int
+	Inlines []Inline // BlockParagraph and BlockHeading
+	Blocks  []Block  // BlockIndent and BlockBottom
+}
+
+// Inline is an inline element of Block
+type Inline struct {
+	Kind       InlineKind
+	Text       string   // text, ruby base or gaiji character
+	Ruby       string   // ruby reading
+	Style      string   // style of emphasis like "傍点", "丸傍点" or "傍線"
+	Annotation string   // body of gaiji annotation or note (inside `［＃…］`)
+	Code       JISCode  // JIS X 0213 code of gaiji
+	Children   []Inline // InlineEmphasis and InlineTateChuYoko
+}
+
+// PlainText returns the text of inlines without ruby and annotations
+func PlainText(inlines []Inline) string {
+	var b strings.Builder
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineText, InlineRuby, InlineGaiji:
+			b.WriteString(in.Text)
+		case InlineEmphasis, InlineTateChuYoko:
+			b.WriteString(PlainText(in.Children))
+		}
+	}
+	return b.String()
+}
+
+// emphasisStyles are the styles of emphasis in Aozora Bunko format
+var emphasisStyles = []string{
+	"傍点", "白ゴマ傍点", "丸傍点", "白丸傍点", "黒三角傍点", "白三角傍点",
+	"二重丸傍点", "蛇の目傍点", "ばつ傍点",
+	"傍線", "二重傍線", "鎖線", "破線", "波線",
+	"太字", "斜体",
+}
+
+// midashiLevels are the levels of headings
+var midashiLevels = map[string]int{"大見出し": 1, "中見出し": 2, "小見出し": 3}
+
+// notationEscapes are the annotations of characters used in the notation,
+// which are read as the characters in text
+var notationEscapes = map[string]string{
+	"始め角括弧、1-1-46":    "［",
+	"始め二重山括弧、1-1-52":  "《",
+	"終わり二重山括弧、1-1-53": "》",
+	"縦線、1-1-35":       "｜",
+}
+
+var (
+	docTokenRe    = regexp.MustCompile(`※［＃[^］]*］|［＃[^］]*］|｜[^｜《》\n]+《[^《》\n]*》|《[^《》\n]*》`)
+	docRangeRe    = regexp.MustCompile(`^「(.+)」(?:に|は)(.+)$`)
+	docIndentRe   = regexp.MustCompile(`^([０-９0-9]+)字下げ$`)
+	docBlockRe    = regexp.MustCompile(`^ここから([０-９0-9]+)字下げ`)
+	docBottomRe   = regexp.MustCompile(`^地から([０-９0-9]+)字上げ$`)
+	docBottomBlRe = regexp.MustCompile(`^ここから地から([０-９0-9]+)字上げ$`)
+	docSeparator  = regexp.MustCompile(`^-{10,}$`)
+)
+
+// ParseDocument parses a work in Aozora Bunko format (Unicode).
+// The lines before the first empty line are the title and the author,
+// and the explanation of symbols between `-------` lines is skipped.
+func ParseDocument(str string) *Document {
+	lines := strings.Split(strings.TrimSuffix(ConvLineEnding(ConvBOM(str, BOMStrip), EOLLF), "\n"), "\n")
+	doc := &Document{}
+
+	var header []string
+	for len(lines) > 0 && lines[0] != "" {
+		header = append(header, lines[0])
+		lines = lines[1:]
+	}
+	if len(header) > 0 {
+		doc.Title = header[0]
+	}
+	if len(header) > 1 {
+		doc.Author = header[len(header)-1]
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && docSeparator.MatchString(lines[0]) {
+		for i := 1; i < len(lines); i++ {
+			if docSeparator.MatchString(lines[i]) {
+				lines = lines[i+1:]
+				break
+			}
+		}
+		for len(lines) > 0 && lines[0] == "" {
+			lines = lines[1:]
+		}
+	}
+
+	p := &docParser{}
+	for _, line := range lines {
+		p.line(line)
+	}
+	for len(p.stack) > 0 {
+		p.pop()
+	}
+	doc.Blocks = p.blocks
+	return doc
+}
+
+// docParser is the state of ParseDocument
+type docParser struct {
+	blocks []Block
+	stack  []Block // open BlockIndent and BlockBottom; their parents are in stack or blocks
+}
+
+func (p *docParser) add(b Block) {
+	if n := len(p.stack); n > 0 {
+		p.stack[n-1].Blocks = append(p.stack[n-1].Blocks, b)
+	} else {
+		p.blocks = append(p.blocks, b)
+	}
+}
+
+func (p *docParser) pop() {
+	n := len(p.stack)
+	b := p.stack[n-1]
+	p.stack = p.stack[:n-1]
+	p.add(b)
+}
+
+// popKind closes the innermost open block of kind
+func (p *docParser) popKind(kind BlockKind) {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].Kind == kind {
+			for len(p.stack) > i {
+				p.pop()
+			}
+			return
+		}
+	}
+}
+
+func (p *docParser) line(line string) {
+	// block annotations on a line by itself
+	if strings.HasPrefix(line, "［＃") && strings.HasSuffix(line, "］") && strings.Count(line, "［＃") == 1 {
+		body := strings.TrimSuffix(strings.TrimPrefix(line, "［＃"), "］")
+		switch {
+		case body == "改ページ" || body == "改丁" || body == "改段" || body == "改見開き":
+			p.add(Block{Kind: BlockPageBreak})
+			return
+		case docBlockRe.MatchString(body):
+			p.stack = append(p.stack, Block{Kind: BlockIndent, Level: atoiWide(docBlockRe.FindStringSubmatch(body)[1])})
+			return
+		case body == "ここから地付き":
+			p.stack = append(p.stack, Block{Kind: BlockBottom})
+			return
+		case docBottomBlRe.MatchString(body):
+			p.stack = append(p.stack, Block{Kind: BlockBottom, Level: atoiWide(docBottomBlRe.FindStringSubmatch(body)[1])})
+			return
+		case body == "ここで字下げ終わり":
+			p.popKind(BlockIndent)
+			return
+		case body == "ここで地付き終わり" || body == "ここで字上げ終わり":
+			p.popKind(BlockBottom)
+			return
+		}
+	}
+
+	var wrap *Block
+	if strings.HasPrefix(line, "［＃") {
+		if i := strings.Index(line, "］"); i > 0 {
+			body := line[len("［＃"):i]
+			switch {
+			case docIndentRe.MatchString(body):
+				wrap = &Block{Kind: BlockIndent, Level: atoiWide(docIndentRe.FindStringSubmatch(body)[1])}
+			case body == "地付き":
+				wrap = &Block{Kind: BlockBottom}
+			case docBottomRe.MatchString(body):
+				wrap = &Block{Kind: BlockBottom, Level: atoiWide(docBottomRe.FindStringSubmatch(body)[1])}
+			}
+			if wrap != nil {
+				line = line[i+len("］"):]
+			}
+		}
+	}
+
+	b := Block{Kind: BlockParagraph}
+	b.Inlines, b.Level = parseInlines(line)
+	if b.Level > 0 {
+		b.Kind = BlockHeading
+	}
+	if wrap != nil {
+		wrap.Blocks = []Block{b}
+		b = *wrap
+	}
+	p.add(b)
+}
+
+// parseInlines parses a line into inlines; level is the level of heading
+// if the line has a heading annotation
+func parseInlines(line string) (inlines []Inline, level int) {
+	// starts of range annotations like `［＃傍点］`: style and position in inlines
+	type open struct {
+		style string
+		pos   int
+	}
+	var opens []open
+
+	addText := func(s string) {
+		if s == "" {
+			return
+		}
+		if n := len(inlines); n > 0 && inlines[n-1].Kind == InlineText {
+			inlines[n-1].Text += s
+			return
+		}
+		inlines = append(inlines, Inline{Kind: InlineText, Text: s})
+	}
+
+	last := 0
+	for _, loc := range docTokenRe.FindAllStringIndex(line, -1) {
+		addText(line[last:loc[0]])
+		last = loc[1]
+		tok := line[loc[0]:loc[1]]
+		switch {
+		case strings.HasPrefix(tok, "※［＃"):
+			in := Inline{Kind: InlineGaiji, Annotation: tok[len("※［＃") : len(tok)-len("］")]}
+			if s, ok := notationEscapes[in.Annotation]; ok {
+				addText(s)
+				continue
+			}
+			if g, err := ParseGaiji(tok); err == nil {
+				in.Text, in.Code = g.Char, g.Code
+			}
+			inlines = append(inlines, in)
+		case strings.HasPrefix(tok, "｜"):
+			i := strings.Index(tok, "《")
+			inlines = append(inlines, Inline{Kind: InlineRuby, Text: tok[len("｜"):i], Ruby: tok[i+len("《") : len(tok)-len("》")]})
+		case strings.HasPrefix(tok, "《"):
+			ruby := tok[len("《") : len(tok)-len("》")]
+			if !rubyBase(&inlines, ruby) {
+				addText(tok)
+			}
+		default:
+			body := tok[len("［＃") : len(tok)-len("］")]
+			if m := docRangeRe.FindStringSubmatch(body); m != nil {
+				if l, ok := midashiLevels[m[2]]; ok {
+					level = l
+					continue
+				}
+				if kind, style, ok := rangeStyle(m[2]); ok {
+					if wrapInlines(&inlines, m[1], kind, style) {
+						continue
+					}
+				}
+			} else if l, ok := midashiLevels[body]; ok {
+				level = l
+				continue
+			} else if _, style, ok := rangeStyle(body); ok {
+				opens = append(opens, open{style, len(inlines)})
+				continue
+			} else if _, ok := midashiLevels[strings.TrimSuffix(body, "終わり")]; ok {
+				continue
+			} else if strings.HasSuffix(body, "終わり") && len(opens) > 0 {
+				name := strings.TrimSuffix(body, "終わり")
+				o := opens[len(opens)-1]
+				if kind, style, ok := rangeStyle(name); ok && style == o.style {
+					opens = opens[:len(opens)-1]
+					children := append([]Inline(nil), inlines[o.pos:]...)
+					inlines = append(inlines[:o.pos], Inline{Kind: kind, Style: style, Children: children})
+					continue
+				}
+			}
+			inlines = append(inlines, Inline{Kind: InlineNote, Annotation: body})
+		}
+	}
+	addText(line[last:])
+	return inlines, level
+}
+
+// rangeStyle returns the kind of inline for the name of annotation like "傍点" or "縦中横"
+func rangeStyle(name string) (InlineKind, string, bool) {
+	if name == "縦中横" {
+		return InlineTateChuYoko, "", true
+	}
+	for _, s := range emphasisStyles {
+		if name == s {
+			return InlineEmphasis, s, true
+		}
+	}
+	return InlineText, "", false
+}
+
+// rubyBase makes ruby of the kanji at the end of inlines
+func rubyBase(inlines *[]Inline, ruby string) bool {
+	n := len(*inlines)
+	if n == 0 || (*inlines)[n-1].Kind != InlineText {
+		return false
+	}
+	r := []rune((*inlines)[n-1].Text)
+	i := len(r)
+	for i > 0 && isRubyKanji(r[i-1]) {
+		i--
+	}
+	if i == len(r) {
+		return false
+	}
+	(*inlines)[n-1].Text = string(r[:i])
+	base := Inline{Kind: InlineRuby, Text: string(r[i:]), Ruby: ruby}
+	if i == 0 {
+		(*inlines)[n-1] = base
+	} else {
+		*inlines = append(*inlines, base)
+	}
+	return true
+}
+
+// wrapInlines wraps inlines at the end matching target into an inline of kind
+func wrapInlines(inlines *[]Inline, target string, kind InlineKind, style string) bool {
+	rest := target
+	start := len(*inlines)
+	for start > 0 && rest != "" {
+		in := (*inlines)[start-1]
+		text := PlainText([]Inline{in})
+		if in.Kind == InlineGaiji {
+			text = "※［＃" + in.Annotation + "］"
+		}
+		switch {
+		case text != "" && strings.HasSuffix(rest, text):
+			rest = rest[:len(rest)-len(text)]
+			start--
+		case in.Kind == InlineText && strings.HasSuffix(text, rest):
+			head := text[:len(text)-len(rest)]
+			(*inlines)[start-1].Text = head
+			tail := Inline{Kind: InlineText, Text: rest}
+			*inlines = append((*inlines)[:start], append([]Inline{tail}, (*inlines)[start:]...)...)
+			rest = ""
+		default:
+			return false
+		}
+	}
+	if rest != "" {
+		return false
+	}
+	children := append([]Inline(nil), (*inlines)[start:]...)
+	*inlines = append((*inlines)[:start], Inline{Kind: kind, Style: style, Children: children})
+	return true
+}
+
+// atoiWide converts full-width or ASCII digits into int
+func atoiWide(s string) int {
+	n, _ := strconv.Atoi(strings.Map(func(r rune) rune {
+		if '０' <= r && r <= '９' {
+			return r - '０' + '0'
+		}
+		return r
+	}, s))
+	return n
+}
@@ -0,0 +1,95 @@
+package aozoraconv
+
+import (
+	"reflect"
+	"testing"
+)
+
+const documentSample = "吾輩は猫である\n夏目漱石\n\n" +
+	"-------------------------------------------------------\n" +
+	"【テキスト中に現れる記号について】\n" +
+	"-------------------------------------------------------\n" +
+	"\n" +
+	"［＃３字下げ］一［＃「一」は大見出し］\n" +
+	"　吾輩《わがはい》は｜猫である《ねこである》。\n" +
+	"じめじめ［＃「じめじめ」に傍点］した所で※［＃「てへん＋劣」、第3水準1-84-77］いた。\n" +
+	"［＃ここから２字下げ］\n" +
+	"明治３８［＃「３８」は縦中横］年\n" +
+	"［＃傍線］強調［＃傍線終わり］［＃不明な注記］\n" +
+	"［＃ここで字下げ終わり］\n" +
+	"［＃改ページ］\n" +
+	"［＃地付き］夏目\n"
+
+func TestParseDocument(t *testing.T) {
+	doc := ParseDocument(documentSample)
+	if doc.Title != "吾輩は猫である" || doc.Author != "夏目漱石" {
+		t.Errorf("ParseDocument got title: %q author: %q", doc.Title, doc.Author)
+	}
+	want := []Block{
+		{Kind: BlockIndent, Level: 3, Blocks: []Block{
+			{Kind: BlockHeading, Level: 1, Inlines: []Inline{{Kind: InlineText, Text: "一"}}},
+		}},
+		{Kind: BlockParagraph, Inlines: []Inline{
+			{Kind: InlineText, Text: "　"},
+			{Kind: InlineRuby, Text: "吾輩", Ruby: "わがはい"},
+			{Kind: InlineText, Text: "は"},
+			{Kind: InlineRuby, Text: "猫である", Ruby: "ねこである"},
+			{Kind: InlineText, Text: "。"},
+		}},
+		{Kind: BlockParagraph, Inlines: []Inline{
+			{Kind: InlineEmphasis, Style: "傍点", Children: []Inline{{Kind: InlineText, Text: "じめじめ"}}},
+			{Kind: InlineText, Text: "した所で"},
+			{Kind: InlineGaiji, Text: "挘", Annotation: "「てへん＋劣」、第3水準1-84-77", Code: JISCode{1, 84, 77}},
+			{Kind: InlineText, Text: "いた。"},
+		}},
+		{Kind: BlockIndent, Level: 2, Blocks: []Block{
+			{Kind: BlockParagraph, Inlines: []Inline{
+				{Kind: InlineText, Text: "明治"},
+				{Kind: InlineTateChuYoko, Children: []Inline{{Kind: InlineText, Text: "３８"}}},
+				{Kind: InlineText, Text: "年"},
+			}},
+			{Kind: BlockParagraph, Inlines: []Inline{
+				{Kind: InlineEmphasis, Style: "傍線", Children: []Inline{{Kind: InlineText, Text: "強調"}}},
+				{Kind: InlineNote, Annotation: "不明な注記"},
+			}},
+		}},
+		{Kind: BlockPageBreak},
+		{Kind: BlockBottom, Blocks: []Block{
+			{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "夏目"}}},
+		}},
+	}
+	if len(doc.Blocks) != len(want) {
+		t.Fatalf("ParseDocument got %d blocks want %d: %+v", len(doc.Blocks), len(want), doc.Blocks)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(doc.Blocks[i], want[i]) {
+			t.Errorf("ParseDocument block %d got: %+v want: %+v", i, doc.Blocks[i], want[i])
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	inlines, _ := parseInlines("｜漢字《かんじ》と強調［＃「強調」に傍点］")
+	if got, want := PlainText(inlines), "漢字と強調"; got != want {
+		t.Errorf("PlainText got: %q want: %q", got, want)
+	}
+}
+
+func TestParseInlinesHeading(t *testing.T) {
+	testcases := []struct {
+		in    string
+		text  string
+		level int
+	}{
+		{"一［＃「一」は大見出し］", "一", 1},
+		{"［＃中見出し］二［＃中見出し終わり］", "二", 2},
+		{"三［＃小見出し］", "三", 3},
+		{"本文", "本文", 0},
+	}
+	for _, tc := range testcases {
+		inlines, level := parseInlines(tc.in)
+		if got := PlainText(inlines); got != tc.text || level != tc.level || len(inlines) != 1 {
+			t.Errorf("parseInlines(%q) got: %q, %d want: %q, %d", tc.in, got, level, tc.text, tc.level)
+		}
+	}
+}
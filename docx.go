@@ -0,0 +1,255 @@
+package aozoraconv
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Size of text in DOCX: 10.5pt in half-points and the width of a character in twips
+const (
+	docxFontSize  = 21
+	docxRubySize  = 10
+	docxCharWidth = 210
+)
+
+// docxEmphasis are the values of `w:em` for 傍点 styles
+var docxEmphasis = map[string]string{
+	"傍点":    "comma",
+	"白ゴマ傍点": "comma",
+	"丸傍点":   "dot",
+	"白丸傍点":  "circle",
+	"二重丸傍点": "circle",
+	"蛇の目傍点": "circle",
+}
+
+// docxUnderline are the values of `w:u` for 傍線 styles
+var docxUnderline = map[string]string{
+	"傍線":   "single",
+	"二重傍線": "double",
+	"鎖線":   "dotDash",
+	"破線":   "dash",
+	"波線":   "wave",
+}
+
+// docxRun are the properties of a run
+type docxRun struct {
+	bold, italic bool
+	size         int // in half-points; 0 is default
+	em, u        string
+	tcy          int // id of 縦中横; 0 is not in 縦中横
+}
+
+// docxBody is document.xml being written with the last id of 縦中横
+type docxBody struct {
+	strings.Builder
+	tcy int
+}
+
+func (r docxRun) properties() string {
+	var b strings.Builder
+	if r.bold {
+		b.WriteString("<w:b/>")
+	}
+	if r.italic {
+		b.WriteString("<w:i/>")
+	}
+	if r.size > 0 {
+		fmt.Fprintf(&b, "<w:sz w:val=\"%d\"/>", r.size)
+	}
+	if r.u != "" {
+		fmt.Fprintf(&b, "<w:u w:val=\"%s\"/>", r.u)
+	}
+	if r.em != "" {
+		fmt.Fprintf(&b, "<w:em w:val=\"%s\"/>", r.em)
+	}
+	if r.tcy > 0 {
+		fmt.Fprintf(&b, "<w:eastAsianLayout w:id=\"%d\" w:vert=\"true\"/>", r.tcy)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<w:rPr>" + b.String() + "</w:rPr>"
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>
+`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>
+`
+
+const docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>
+`
+
+const docxCore = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:title>%s</dc:title>
+<dc:creator>%s</dc:creator>
+<dc:language>ja-JP</dc:language>
+</cp:coreProperties>
+`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults>
+<w:rPrDefault><w:rPr><w:rFonts w:eastAsia="游明朝"/><w:sz w:val="21"/><w:lang w:eastAsia="ja-JP"/></w:rPr></w:rPrDefault>
+</w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:rPr><w:sz w:val="32"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:pPr><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:pPr><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>
+</w:styles>
+`
+
+// WriteDOCX writes doc as a Word document (WordprocessingML) in vertical layout.
+// Ruby is `w:ruby`, 傍点 is `w:em`, 字下げ and 地付き blocks are paragraph
+// indentation, and 改ページ is a page break. Annotations not interpreted are written as text.
+func WriteDOCX(w io.Writer, doc *Document) error {
+	var body docxBody
+	if doc.Title != "" {
+		fmt.Fprintf(&body, "<w:p><w:pPr><w:pStyle w:val=\"Title\"/></w:pPr>%s</w:p>\n", docxText(doc.Title, docxRun{}))
+	}
+	if doc.Author != "" {
+		fmt.Fprintf(&body, "<w:p><w:pPr><w:jc w:val=\"right\"/></w:pPr>%s</w:p>\n", docxText(doc.Author, docxRun{}))
+	}
+	docxBlocks(&body, doc.Blocks, 0, -1)
+
+	var document strings.Builder
+	document.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	document.WriteString("<w:document xmlns:w=\"http://schemas.openxmlformats.org/wordprocessingml/2006/main\">\n<w:body>\n")
+	document.WriteString(body.String())
+	document.WriteString("<w:sectPr><w:pgSz w:w=\"11906\" w:h=\"16838\"/>" +
+		"<w:pgMar w:top=\"1701\" w:right=\"1701\" w:bottom=\"1701\" w:left=\"1701\" w:header=\"851\" w:footer=\"992\" w:gutter=\"0\"/>" +
+		"<w:textDirection w:val=\"tbRl\"/><w:docGrid w:type=\"lines\" w:linePitch=\"360\"/></w:sectPr>\n")
+	document.WriteString("</w:body>\n</w:document>\n")
+
+	z := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"docProps/core.xml", fmt.Sprintf(docxCore, xmlEscaper.Replace(doc.Title), xmlEscaper.Replace(doc.Author))},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", document.String()},
+	}
+	for _, p := range parts {
+		f, err := z.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// docxBlocks writes blocks; indent is the width of 字下げ and bottom is
+// the width of 地からN字上げ (-1 if not in 地付き)
+func docxBlocks(b *docxBody, blocks []Block, indent, bottom int) {
+	for _, bl := range blocks {
+		switch bl.Kind {
+		case BlockIndent:
+			docxBlocks(b, bl.Blocks, indent+bl.Level, bottom)
+		case BlockBottom:
+			docxBlocks(b, bl.Blocks, indent, bl.Level)
+		case BlockPageBreak:
+			b.WriteString("<w:p><w:r><w:br w:type=\"page\"/></w:r></w:p>\n")
+		default:
+			b.WriteString("<w:p>")
+			b.WriteString(docxParagraphProperties(bl, indent, bottom))
+			docxInlines(b, bl.Inlines, docxRun{})
+			b.WriteString("</w:p>\n")
+		}
+	}
+}
+
+func docxParagraphProperties(bl Block, indent, bottom int) string {
+	var b strings.Builder
+	if bl.Kind == BlockHeading {
+		fmt.Fprintf(&b, "<w:pStyle w:val=\"Heading%d\"/>", bl.Level)
+	}
+	right := 0
+	if bottom > 0 {
+		right = bottom
+	}
+	if indent > 0 || right > 0 {
+		fmt.Fprintf(&b, "<w:ind w:left=\"%d\" w:leftChars=\"%d\" w:right=\"%d\" w:rightChars=\"%d\"/>",
+			indent*docxCharWidth, indent*100, right*docxCharWidth, right*100)
+	}
+	if bottom >= 0 {
+		b.WriteString("<w:jc w:val=\"right\"/>")
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<w:pPr>" + b.String() + "</w:pPr>"
+}
+
+func docxInlines(b *docxBody, inlines []Inline, run docxRun) {
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineText:
+			b.WriteString(docxText(in.Text, run))
+		case InlineRuby:
+			fmt.Fprintf(b, "<w:r>%s<w:ruby><w:rubyPr><w:rubyAlign w:val=\"distributeSpace\"/>"+
+				"<w:hps w:val=\"%d\"/><w:hpsRaise w:val=\"%d\"/><w:hpsBaseText w:val=\"%d\"/><w:lid w:val=\"ja-JP\"/></w:rubyPr>"+
+				"<w:rt>%s</w:rt><w:rubyBase>%s</w:rubyBase></w:ruby></w:r>",
+				run.properties(), docxRubySize, docxFontSize-3, docxFontSize,
+				docxText(in.Ruby, docxRun{size: docxRubySize}), docxText(in.Text, run))
+		case InlineEmphasis:
+			r := run
+			switch {
+			case in.Style == "太字":
+				r.bold = true
+			case in.Style == "斜体":
+				r.italic = true
+			case docxUnderline[in.Style] != "":
+				r.u = docxUnderline[in.Style]
+			case docxEmphasis[in.Style] != "":
+				r.em = docxEmphasis[in.Style]
+			default:
+				r.em = "dot"
+			}
+			docxInlines(b, in.Children, r)
+		case InlineTateChuYoko:
+			// each 縦中横 has its own id, or Word may join adjacent ones
+			b.tcy++
+			r := run
+			r.tcy = b.tcy
+			docxInlines(b, in.Children, r)
+		case InlineGaiji:
+			if in.Text != "" {
+				b.WriteString(docxText(in.Text, run))
+			} else {
+				b.WriteString(docxText("※［＃"+in.Annotation+"］", run))
+			}
+		case InlineNote:
+			b.WriteString(docxText("［＃"+in.Annotation+"］", run))
+		}
+	}
+}
+
+// docxText returns a run of text
+func docxText(text string, run docxRun) string {
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf("<w:r>%s<w:t xml:space=\"preserve\">%s</w:t></w:r>", run.properties(), xmlEscaper.Replace(text))
+}
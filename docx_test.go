@@ -0,0 +1,109 @@
+package aozoraconv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// readDOCX returns the parts of a DOCX file
+func readDOCX(t *testing.T, data []byte) map[string]string {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader error: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) error: %v", f.Name, err)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("ReadAll(%s) error: %v", f.Name, err)
+		}
+		parts[f.Name] = string(b)
+	}
+	return parts
+}
+
+func TestWriteDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDOCX(&buf, ParseDocument(documentSample)); err != nil {
+		t.Fatalf("WriteDOCX error: %v", err)
+	}
+	parts := readDOCX(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "docProps/core.xml", "word/_rels/document.xml.rels", "word/styles.xml", "word/document.xml"} {
+		part, ok := parts[name]
+		if !ok {
+			t.Errorf("WriteDOCX has no %s", name)
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(part))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("WriteDOCX %s is not well-formed: %v", name, err)
+				break
+			}
+		}
+	}
+	if !strings.Contains(parts["docProps/core.xml"], "<dc:title>吾輩は猫である</dc:title>\n<dc:creator>夏目漱石</dc:creator>") {
+		t.Errorf("WriteDOCX core.xml got:\n%s", parts["docProps/core.xml"])
+	}
+
+	got := parts["word/document.xml"]
+	for _, want := range []string{
+		"<w:p><w:pPr><w:pStyle w:val=\"Title\"/></w:pPr><w:r><w:t xml:space=\"preserve\">吾輩は猫である</w:t></w:r></w:p>\n",
+		"<w:p><w:pPr><w:pStyle w:val=\"Heading1\"/><w:ind w:left=\"630\" w:leftChars=\"300\" w:right=\"0\" w:rightChars=\"0\"/></w:pPr><w:r><w:t xml:space=\"preserve\">一</w:t></w:r></w:p>\n",
+		"<w:r><w:ruby><w:rubyPr><w:rubyAlign w:val=\"distributeSpace\"/><w:hps w:val=\"10\"/><w:hpsRaise w:val=\"18\"/><w:hpsBaseText w:val=\"21\"/><w:lid w:val=\"ja-JP\"/></w:rubyPr>" +
+			"<w:rt><w:r><w:rPr><w:sz w:val=\"10\"/></w:rPr><w:t xml:space=\"preserve\">わがはい</w:t></w:r></w:rt>" +
+			"<w:rubyBase><w:r><w:t xml:space=\"preserve\">吾輩</w:t></w:r></w:rubyBase></w:ruby></w:r>",
+		"<w:r><w:rPr><w:em w:val=\"comma\"/></w:rPr><w:t xml:space=\"preserve\">じめじめ</w:t></w:r>",
+		"<w:r><w:t xml:space=\"preserve\">挘</w:t></w:r>",
+		"<w:r><w:rPr><w:eastAsianLayout w:id=\"1\" w:vert=\"true\"/></w:rPr><w:t xml:space=\"preserve\">３８</w:t></w:r>",
+		"<w:r><w:rPr><w:u w:val=\"single\"/></w:rPr><w:t xml:space=\"preserve\">強調</w:t></w:r><w:r><w:t xml:space=\"preserve\">［＃不明な注記］</w:t></w:r>",
+		"<w:p><w:r><w:br w:type=\"page\"/></w:r></w:p>\n<w:p><w:pPr><w:jc w:val=\"right\"/></w:pPr><w:r><w:t xml:space=\"preserve\">夏目</w:t></w:r></w:p>\n",
+		"<w:textDirection w:val=\"tbRl\"/>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteDOCX document.xml got:\n%s\nwant containing: %q", got, want)
+		}
+	}
+}
+
+func TestDocxParagraphProperties(t *testing.T) {
+	testcases := []struct {
+		indent, bottom int
+		want           string
+	}{
+		{0, -1, ""},
+		{2, -1, "<w:pPr><w:ind w:left=\"420\" w:leftChars=\"200\" w:right=\"0\" w:rightChars=\"0\"/></w:pPr>"},
+		{0, 0, "<w:pPr><w:jc w:val=\"right\"/></w:pPr>"},
+		{0, 3, "<w:pPr><w:ind w:left=\"0\" w:leftChars=\"0\" w:right=\"630\" w:rightChars=\"300\"/><w:jc w:val=\"right\"/></w:pPr>"},
+	}
+	for _, tc := range testcases {
+		if got := docxParagraphProperties(Block{Kind: BlockParagraph}, tc.indent, tc.bottom); got != tc.want {
+			t.Errorf("docxParagraphProperties(%d, %d) got: %q want: %q", tc.indent, tc.bottom, got, tc.want)
+		}
+	}
+}
+
+func TestDocxTateChuYokoIDs(t *testing.T) {
+	var b docxBody
+	inlines, _ := parseInlines("１２［＃「１２」は縦中横］月３［＃「３」は縦中横］日")
+	docxInlines(&b, inlines, docxRun{})
+	for _, want := range []string{"w:id=\"1\"", "w:id=\"2\""} {
+		if strings.Count(b.String(), want) != 1 {
+			t.Errorf("docxInlines got: %s want one %s", b.String(), want)
+		}
+	}
+}
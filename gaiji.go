@@ -0,0 +1,196 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	gaijiAnnotationRe = regexp.MustCompile(`※［＃([^［］]*)］`)
+	gaijiUnicodeRe    = regexp.MustCompile(`^U\+([0-9A-Fa-f]{4,6})(?:\+([0-9A-Fa-f]{4,6}))?$`)
+	gaijiJisRe        = regexp.MustCompile(`^(?:第([1-4])水準)?([12])-(\d{1,2})-(\d{1,2})$`)
+	gaijiPageLineRe   = regexp.MustCompile(`^\d+-(?:[上中下]-)?\d+$`)
+)
+
+// Gaiji is a parsed gaiji annotation like `※［＃「口＋七」、第3水準1-47-52、123-4］`
+type Gaiji struct {
+	Description string  // description without brackets (口＋七)
+	Code        JISCode // JIS X 0213 code; zero value if not referred
+	Char        string  // the character referred by JIS X 0213 code or Unicode; empty if unknown
+	PageLine    string  // page and line in the original book (123-4)
+}
+
+// ParseGaiji parses a gaiji annotation (with or without leading ※)
+func ParseGaiji(annotation string) (Gaiji, error) {
+	body := strings.TrimPrefix(annotation, "※")
+	if !strings.HasPrefix(body, "［＃") || !strings.HasSuffix(body, "］") {
+		return Gaiji{}, fmt.Errorf("not a gaiji annotation: %q", annotation)
+	}
+	body = strings.TrimSuffix(strings.TrimPrefix(body, "［＃"), "］")
+	fields := splitAnnotationFields(body)
+	g := Gaiji{Description: fields[0]}
+	if strings.HasPrefix(g.Description, "「") && strings.HasSuffix(g.Description, "」") {
+		g.Description = strings.TrimSuffix(strings.TrimPrefix(g.Description, "「"), "」")
+	}
+	for _, f := range fields[1:] {
+		if u := gaijiUnicodeRe.FindStringSubmatch(f); u != nil {
+			if chr, err := unicodeRef(u[1], u[2]); err == nil {
+				g.Char = chr
+			}
+		} else if gaijiJisRe.MatchString(f) {
+			if code, err := ParseJISCode(f); err == nil {
+				g.Code = code
+			}
+		} else if gaijiPageLineRe.MatchString(f) {
+			g.PageLine = f
+		}
+	}
+	if g.Char == "" && g.Code.IsValid() {
+		g.Char, _ = g.Code.Unicode()
+	}
+	return g, nil
+}
+
+// GaijiChange records a gaiji annotation rewritten by UpgradeGaiji
+type GaijiChange struct {
+	Line int    // 1-origin line number in the input
+	Old  string // annotation before rewriting
+	New  string // annotation after rewriting
+}
+
+// String returns a report line of the change
+func (c GaijiChange) String() string {
+	return fmt.Sprintf("%d: %s -> %s", c.Line, c.Old, c.New)
+}
+
+// UpgradeGaiji rewrites gaiji annotations referring to Unicode code points
+// (`※［＃「…」、U+20B9F、123-4］`) into JIS X 0213 references
+// (`※［＃「…」、第4水準2-4-63、123-4］`) when the character exists in JIS X 0213.
+// It returns the rewritten text and the list of changed annotations.
+func UpgradeGaiji(str string) (string, []GaijiChange) {
+	var changes []GaijiChange
+	var b strings.Builder
+	last, line := 0, 1
+	for _, loc := range gaijiAnnotationRe.FindAllStringSubmatchIndex(str, -1) {
+		line += strings.Count(str[last:loc[0]], "\n")
+		b.WriteString(str[last:loc[0]])
+		last = loc[1]
+
+		old := str[loc[0]:loc[1]]
+		body, ok := upgradeGaijiBody(str[loc[2]:loc[3]])
+		if !ok {
+			b.WriteString(old)
+			continue
+		}
+		annotation := "※［＃" + body + "］"
+		b.WriteString(annotation)
+		if annotation != old {
+			changes = append(changes, GaijiChange{Line: line, Old: old, New: annotation})
+		}
+	}
+	b.WriteString(str[last:])
+	return b.String(), changes
+}
+
+// upgradeGaijiBody returns the body of an annotation with canonical JIS reference
+func upgradeGaijiBody(body string) (string, bool) {
+	fields := splitAnnotationFields(body)
+	if len(fields) < 2 {
+		return body, false
+	}
+	uniIdx, jisIdx := -1, -1
+	var ref string
+	for i, f := range fields[1:] {
+		if m := gaijiUnicodeRe.FindStringSubmatch(f); m != nil && uniIdx < 0 {
+			uniIdx = i + 1
+			chr, err := unicodeRef(m[1], m[2])
+			if err != nil {
+				continue
+			}
+			jis, err := Uni2Jis(chr)
+			if err != nil || Is0208(jis.Men(), jis.Ku(), jis.Ten()) {
+				continue
+			}
+			ref = jis.Ref()
+		} else if m := gaijiJisRe.FindStringSubmatch(f); m != nil && jisIdx < 0 {
+			jisIdx = i + 1
+		}
+	}
+	// the Unicode field is kept unless it is replaced by its own JIS reference
+	fromUnicode := ref != ""
+	if ref == "" && jisIdx > 0 {
+		m := gaijiJisRe.FindStringSubmatch(fields[jisIdx])
+		men, _ := strconv.Atoi(m[2])
+		ku, _ := strconv.Atoi(m[3])
+		ten, _ := strconv.Atoi(m[4])
+		if _, err := Jis2Uni(men, ku, ten); err != nil || Is0208(men, ku, ten) {
+			return body, false
+		}
+		ref = JisRef(men, ku, ten)
+	}
+	if ref == "" {
+		return body, false
+	}
+
+	ret := make([]string, 0, len(fields))
+	for i, f := range fields {
+		switch {
+		case i == uniIdx && jisIdx < 0, i == jisIdx:
+			ret = append(ret, ref)
+		case i == uniIdx && fromUnicode:
+			// dropped; replaced by the JIS reference
+		default:
+			ret = append(ret, f)
+		}
+	}
+	return strings.Join(ret, "、"), true
+}
+
+// splitAnnotationFields splits an annotation body by "、" outside of 「」
+func splitAnnotationFields(body string) []string {
+	var fields []string
+	depth, start := 0, 0
+	for i, r := range body {
+		switch r {
+		case '「':
+			depth++
+		case '」':
+			if depth > 0 {
+				depth--
+			}
+		case '、':
+			if depth == 0 {
+				fields = append(fields, body[start:i])
+				start = i + len("、")
+			}
+		}
+	}
+	return append(fields, body[start:])
+}
+
+// unicodeRef returns a string from hex code points like "20B9F"
+func unicodeRef(hex1, hex2 string) (string, error) {
+	var r []rune
+	for _, h := range []string{hex1, hex2} {
+		if h == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(h, 16, 32)
+		if err != nil {
+			return "", err
+		}
+		r = append(r, rune(v))
+	}
+	return string(r), nil
+}
+
+// JisRef returns men-ku-ten reference in Aozora Bunko gaiji annotation,
+// such as "第3水準1-85-54", "第4水準2-1-1" or "1-13-21" (non-kanji)
+func JisRef(men, ku, ten int) string {
+	if level := KanjiLevel(men, ku, ten); level > 0 {
+		return fmt.Sprintf("第%d水準%d-%d-%d", level, men, ku, ten)
+	}
+	return fmt.Sprintf("%d-%d-%d", men, ku, ten)
+}
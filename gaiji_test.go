@@ -0,0 +1,87 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUpgradeGaiji(t *testing.T) {
+	var convertedPairs = []struct {
+		in      string
+		out     string
+		changed int
+	}{
+		{"※［＃「口＋七」、U+20B9F、123-4］", "※［＃「口＋七」、第3水準1-47-52、123-4］", 1},
+		{"※［＃「にんべん＋乂」、U+20089、45-上-6］", "※［＃「にんべん＋乂」、第4水準2-1-1、45-上-6］", 1},
+		{"※［＃「口＋七」、U+20B9F、1-47-52、123-4］", "※［＃「口＋七」、第3水準1-47-52、123-4］", 1},
+		{"※［＃「口＋七」、1-47-52、123-4］", "※［＃「口＋七」、第3水準1-47-52、123-4］", 1},
+		{"※［＃「口＋七」、U+9FFF、1-47-52、123-4］", "※［＃「口＋七」、U+9FFF、第3水準1-47-52、123-4］", 1},
+		{"※［＃「口＋七」、U+9FFF、第3水準1-47-52、123-4］", "※［＃「口＋七」、U+9FFF、第3水準1-47-52、123-4］", 0},
+		{"※［＃「口＋七」、第3水準1-47-52、123-4］", "※［＃「口＋七」、第3水準1-47-52、123-4］", 0},
+		{"※［＃「亜」、U+4E9C、1-1］", "※［＃「亜」、U+4E9C、1-1］", 0},
+		{"※［＃「☺、☻」、U+263A、1-1］", "※［＃「☺、☻」、U+263A、1-1］", 0},
+		{"※［＃ローマ数字1、U+2160、10-2］", "※［＃ローマ数字1、1-13-21、10-2］", 1},
+		{"［＃ここから２字下げ］", "［＃ここから２字下げ］", 0},
+	}
+	for _, tt := range convertedPairs {
+		got, changes := UpgradeGaiji(tt.in)
+		if got != tt.out {
+			t.Errorf("UpgradeGaiji got: %v want: %v", got, tt.out)
+		}
+		if len(changes) != tt.changed {
+			t.Errorf("UpgradeGaiji %v: got %v changes want %v", tt.in, len(changes), tt.changed)
+		}
+	}
+}
+
+func TestUpgradeGaijiLine(t *testing.T) {
+	in := "一行目\n二行目※［＃「口＋七」、U+20B9F、1-2］\n三行目※［＃「口＋七」、U+20B9F、1-3］\n"
+	_, changes := UpgradeGaiji(in)
+	if len(changes) != 2 {
+		t.Fatalf("UpgradeGaiji got %v changes want 2", len(changes))
+	}
+	if changes[0].Line != 2 || changes[1].Line != 3 {
+		t.Errorf("UpgradeGaiji lines got: %v, %v want: 2, 3", changes[0].Line, changes[1].Line)
+	}
+}
+
+func TestDecodeWithUpgradeGaiji(t *testing.T) {
+	in := toSjis("※［＃「口＋七」、U+20B9F、123-4］")
+	output := new(bytes.Buffer)
+	var changes []GaijiChange
+	if err := Decode(bytes.NewBuffer(in), output, WithUpgradeGaiji(&changes)); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got, want := output.String(), "※［＃「口＋七」、第3水準1-47-52、123-4］"; got != want {
+		t.Errorf("Decode got: %v want: %v", got, want)
+	}
+	if len(changes) != 1 {
+		t.Errorf("Decode got %v changes want 1", len(changes))
+	}
+
+	output.Reset()
+	if err := Encode(strings.NewReader("※［＃「口＋七」、U+20B9F、123-4］"), output, WithUpgradeGaiji(nil)); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("※［＃「口＋七」、第3水準1-47-52、123-4］"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+}
+
+func TestJisRef(t *testing.T) {
+	var convertedPairs = []struct {
+		men, ku, ten int
+		out          string
+	}{
+		{1, 85, 54, "第3水準1-85-54"},
+		{1, 14, 1, "第3水準1-14-1"},
+		{2, 1, 1, "第4水準2-1-1"},
+		{1, 13, 21, "1-13-21"},
+	}
+	for _, tt := range convertedPairs {
+		if got := JisRef(tt.men, tt.ku, tt.ten); got != tt.out {
+			t.Errorf("JisRef got: %v want: %v", got, tt.out)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Package tablegen generates tables.go of aozoraconv from the mapping table of
+// JIS X 0213 (http://x0213.org/codetable/jisx0213-2004-std.txt).
+// It is used by maketables.go and its tests only, so that programs using
+// aozoraconv do not link the generator.
+package tablegen
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	commentYearRe      = regexp.MustCompile(`\[(2000|2004)\]`)
+	commentFullwidthRe = regexp.MustCompile(`Fullwidth: U\+([0-9A-Fa-f]+)`)
+)
+
+// jisCode is a code point of JIS X 0213 with men (plane), ku (row) and ten (cell)
+type jisCode struct {
+	men, ku, ten int
+}
+
+// jisPair is a mapping between a pair of runes and JIS code
+type jisPair struct {
+	r1, r2 rune
+	code   jisCode
+}
+
+// parseLine parses single line and returns values
+func parseLine(s string, m, k, t *int, uni, uni2 *int32) error {
+	var err error
+	if _, err = fmt.Sscanf(s, "%d-%02X%02X	U+%X+%X	", m, k, t, uni, uni2); err == nil {
+		return nil
+	} else if _, err = fmt.Sscanf(s, "%d-%02X%02X	U+%X	", m, k, t, uni); err == nil {
+		return nil
+	} else if _, err = fmt.Sscanf(s, "%d-%02X%02X		", m, k, t); err == nil {
+		return nil
+	}
+	return fmt.Errorf("could not parse %q; %v", s, err)
+}
+
+// parseComment parses the comment of single line and returns the year
+// marked as [2000] or [2004] (0 if not marked) and the alternative mapping
+// marked as "Fullwidth: U+XXXX" (0 if none)
+func parseComment(s string) (year int, fullwidth int32) {
+	i := strings.Index(s, "#")
+	if i < 0 {
+		return 0, 0
+	}
+	s = s[i:]
+	if m := commentYearRe.FindStringSubmatch(s); m != nil {
+		year, _ = strconv.Atoi(m[1])
+	}
+	if m := commentFullwidthRe.FindStringSubmatch(s); m != nil {
+		v, _ := strconv.ParseInt(m[1], 16, 32)
+		fullwidth = int32(v)
+	}
+	return year, fullwidth
+}
+
+// sha256Sum returns hex string of SHA-256 checksum of data
+func sha256Sum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks data against pinned checksum in sha256sum format
+// ("<hex>  <filename>")
+func Verify(data []byte, pinned string) error {
+	fields := strings.Fields(pinned)
+	if len(fields) == 0 {
+		return fmt.Errorf("pinned checksum is empty")
+	}
+	if got, want := sha256Sum(data), strings.ToLower(fields[0]); got != want {
+		return fmt.Errorf("SHA-256 mismatch: got %s, pinned %s", got, want)
+	}
+	return nil
+}
+
+// sourceVersion returns version of mapping table from "## Date:" and
+// "## Version:" lines in the header
+func sourceVersion(data []byte) string {
+	var ret []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		s := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(s, "#") {
+			break
+		}
+		s = strings.TrimSpace(strings.TrimLeft(s, "#"))
+		if strings.HasPrefix(s, "Date:") || strings.HasPrefix(s, "Version:") {
+			ret = append(ret, s)
+		}
+	}
+	if len(ret) == 0 {
+		return "unknown version"
+	}
+	return strings.Join(ret, ", ")
+}
+
+// tableEntry is a line of the mapping table
+type tableEntry struct {
+	code      jisCode
+	r1, r2    rune
+	year      int
+	fullwidth rune
+}
+
+// readTable parses the mapping table of JIS X 0213
+func readTable(data []byte) ([]tableEntry, error) {
+	var ret []tableEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		m, k, t, uni, uni2 := 0, 0, 0, int32(0), int32(0)
+		if err := parseLine(s, &m, &k, &t, &uni, &uni2); err != nil {
+			return nil, err
+		}
+		m -= 2
+		k -= 32
+		t -= 32
+		if m < 1 || 2 < m || k < 1 || 94 < k || t < 1 || 94 < t {
+			return nil, fmt.Errorf("JIS code %d-%d-%d is out of range: %q", m, k, t, s)
+		}
+		e := tableEntry{code: jisCode{m, k, t}, r1: uni, r2: uni2}
+		e.year, e.fullwidth = parseComment(s)
+		ret = append(ret, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Generate returns tables.go generated from the mapping table; src is the name
+// of the table recorded in the header
+func Generate(data []byte, src string) ([]byte, error) {
+	entries, err := readTable(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping [2][94][94]string
+	reverse := map[rune]jisCode{}
+	var pairs, fullwidth []jisPair
+	var added2004 []jisCode
+	for _, e := range entries {
+		c := e.code
+		switch {
+		case e.r2 > 0:
+			mapping[c.men-1][c.ku-1][c.ten-1] = string([]rune{e.r1, e.r2})
+			pairs = append(pairs, jisPair{e.r1, e.r2, c})
+		case e.r1 > 0:
+			mapping[c.men-1][c.ku-1][c.ten-1] = string(e.r1)
+			if d, ok := reverse[e.r1]; ok {
+				return nil, fmt.Errorf("%U is duplicated, %v and %v", e.r1, c, d)
+			}
+			reverse[e.r1] = c
+		}
+		if e.year == 2004 {
+			added2004 = append(added2004, c)
+		}
+		if e.fullwidth > 0 {
+			fullwidth = append(fullwidth, jisPair{e.fullwidth, 0, c})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].r1 != pairs[j].r1 {
+			return pairs[i].r1 < pairs[j].r1
+		}
+		return pairs[i].r2 < pairs[j].r2
+	})
+	sort.Slice(fullwidth, func(i, j int) bool { return fullwidth[i].r1 < fullwidth[j].r1 })
+
+	var out bytes.Buffer
+	printf := func(format string, a ...interface{}) {
+		fmt.Fprintf(&out, format, a...)
+	}
+
+	printf("// Code generated by maketables.go; DO NOT EDIT.\n")
+	printf("// Source: %s (%s)\n", src, sourceVersion(data))
+	printf("// SHA-256: %s\n\n", sha256Sum(data))
+	printf("// Package aozoraconv provides Aozora Bunko format encodings (JIS X 0208/Shift_JIS).\n")
+	printf(`package aozoraconv // import "github.com/takahashim/aozoraconv"` + "\n\n")
+
+	printf("// JisEntry is jis character with men, ku, ten.\n//\n")
+	printf("// Deprecated: JisEntry is an alias of JISCode.\n")
+	printf("type JisEntry = JISCode\n\n")
+
+	printf("// jisPair is a mapping between a pair of runes and JIS code.\n")
+	printf("// r2 is 0 for a mapping of a single rune.\n")
+	printf("type jisPair struct {\n\tr1, r2 rune\n\tcode   JISCode\n}\n\n")
+
+	// decoded characters are packed into one string in the order of JIS code;
+	// lenBits is the number of bits for the length of a character
+	const lenBits = 4
+	var chars strings.Builder
+	var offsets [2 * 94 * 94]int
+	for m, m1 := range mapping {
+		for k, m2 := range m1 {
+			for t, m3 := range m2 {
+				if m3 != "" {
+					offsets[(m*94+k)*94+t] = chars.Len()<<lenBits | len(m3)
+					chars.WriteString(m3)
+				}
+			}
+		}
+	}
+
+	printf("const decodeLenBits = %d\n\n", lenBits)
+
+	printf("// jis0213Decode is the decoding table from JIS 0213 code to Unicode,\n")
+	printf("// indexed by ((men-1)*94+(ku-1))*94+(ten-1).\n")
+	printf("// It is defined at http://x0213.org/codetable/jisx0213-2004-std.txt\n")
+	printf("//\n")
+	printf("// A value is offset<<decodeLenBits | length of the character in jis0213Chars.\n")
+	printf("// 0 means no character.\n")
+	printf("var jis0213Decode = [2 * 94 * 94]uint32{\n")
+	for m, m1 := range mapping {
+		for k, m2 := range m1 {
+			last := len(m2) - 1
+			for last >= 0 && m2[last] == "" {
+				last--
+			}
+			if last < 0 {
+				continue
+			}
+			printf("\t// %d-%d\n", m+1, k+1)
+			printf("\t%d: ", (m*94+k)*94)
+			for t := range m2[:last+1] {
+				if t%8 == 0 && t > 0 {
+					printf("\n\t")
+				}
+				printf("0x%06x, ", offsets[(m*94+k)*94+t])
+			}
+			printf("\n")
+		}
+	}
+	printf("}\n\n")
+
+	printf("// jis0213Chars are the characters of JIS X 0213 in the order of JIS code.\n")
+	printf("const jis0213Chars = \"\" +\n")
+	for m, m1 := range mapping {
+		for k, m2 := range m1 {
+			row := strings.Join(m2[:], "")
+			if row == "" {
+				continue
+			}
+			printf("\t%q + // %d-%d\n", row, m+1, k+1)
+		}
+	}
+	printf("\t\"\"\n\n")
+
+	// runes are looked up with a two-level trie: the high bits select a block of
+	// the index and the low bits select an entry in the block. Identical blocks
+	// (mostly empty ones) are shared.
+	const blockShift = 5
+	const blockSize = 1 << blockShift
+
+	maxRune := rune(0)
+	for r := range reverse {
+		if r > maxRune {
+			maxRune = r
+		}
+	}
+	numBlocks := int(maxRune>>blockShift) + 1
+	blocks := [][blockSize]uint16{{}}
+	blockIndex := map[[blockSize]uint16]int{blocks[0]: 0}
+	index := make([]int, numBlocks)
+	for n := 0; n < numBlocks; n++ {
+		var b [blockSize]uint16
+		for j := range b {
+			if c, ok := reverse[rune(n<<blockShift+j)]; ok {
+				b[j] = uint16(int(c.men)<<14 | int(c.ku)<<7 | int(c.ten))
+			}
+		}
+		i, ok := blockIndex[b]
+		if !ok {
+			i = len(blocks)
+			blocks = append(blocks, b)
+			blockIndex[b] = i
+		}
+		index[n] = i
+	}
+
+	printf("const (\n")
+	printf("\tcodeMask   = 0x7f\n")
+	printf("\tcodeShift  = 7\n")
+	printf("\tplaneShift = 14\n")
+	printf(")\n\n")
+
+	printf("const (\n")
+	printf("\tencodeBlockShift = %d\n", blockShift)
+	printf("\tencodeBlockMask  = 0x%x\n", blockSize-1)
+	printf("\tencodeMaxRune    = 0x%x\n", numBlocks<<blockShift)
+	printf(")\n\n")
+
+	printf("// jis0213EncodeIndex is the first level of the encoding trie from Unicode\n")
+	printf("// to JIS code. It maps r>>encodeBlockShift to a block of jis0213Encode.\n")
+	printf("var jis0213EncodeIndex = [...]uint16{\n")
+	for n, i := range index {
+		if n%16 == 0 {
+			printf("\t")
+		}
+		printf("%d, ", i)
+		if n%16 == 15 || n == len(index)-1 {
+			printf("\n")
+		}
+	}
+	printf("}\n\n")
+
+	printf("// jis0213Encode is the second level of the encoding trie, %d blocks of\n", len(blocks))
+	printf("// %d entries. Block 0 is empty.\n", blockSize)
+	printf("//\n")
+	printf("// The high two bits of the value are the plane (men) of JIS X 0213,\n")
+	printf("// and the low 14 bits are two 7-bit unsigned integers ku and ten.\n")
+	printf("// 0 means no JIS code.\n")
+	printf("var jis0213Encode = [...]uint16{\n")
+	for i, b := range blocks {
+		if i == 0 {
+			printf("\t// block 0\n")
+		}
+		for n, bi := range index {
+			if bi == i && i > 0 {
+				printf("\t// block %d: U+%04X\n", i, n<<blockShift)
+				break
+			}
+		}
+		for j, v := range b {
+			if j%8 == 0 {
+				printf("\t")
+			}
+			printf("0x%04x, ", v)
+			if j%8 == 7 {
+				printf("\n")
+			}
+		}
+	}
+	printf("}\n\n")
+
+	printf("// jis0213Pairs are the mappings of pairs of runes (combining sequences),\n")
+	printf("// sorted by runes.\n")
+	printf("var jis0213Pairs = [...]jisPair{\n")
+	for _, p := range pairs {
+		printf("\t{0x%04X, 0x%04X, JISCode{%d, %d, %d}}, // %q\n", p.r1, p.r2, p.code.men, p.code.ku, p.code.ten, string([]rune{p.r1, p.r2}))
+	}
+	printf("}\n\n")
+
+	printf("// jis0213Added2004 are the characters added in JIS X 0213:2004 (marked as [2004]).\n")
+	printf("var jis0213Added2004 = [...]JisEntry{\n")
+	for _, c := range added2004 {
+		printf("\t{men: %d, ku: %d, ten: %d}, // %q\n", c.men, c.ku, c.ten, mapping[c.men-1][c.ku-1][c.ten-1])
+	}
+	printf("}\n\n")
+
+	printf("// jis0213Fullwidth are the alternative mappings of fullwidth forms (marked as Fullwidth:),\n")
+	printf("// sorted by runes.\n")
+	printf("var jis0213Fullwidth = [...]jisPair{\n")
+	for _, p := range fullwidth {
+		printf("\t{0x%04X, 0, JISCode{%d, %d, %d}}, // %q\n", p.r1, p.code.men, p.code.ku, p.code.ten, string(p.r1))
+	}
+	printf("}\n")
+
+	return format.Source(out.Bytes())
+}
@@ -0,0 +1,85 @@
+package tablegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseComment(test *testing.T) {
+	var convertedPairs = []struct {
+		in        string
+		year      int
+		fullwidth int32
+	}{
+		{"3-2121\tU+3000\t# IDEOGRAPHIC SPACE", 0, 0},
+		{"3-2477\tU+304B+309A\t# \t[2000]", 2000, 0},
+		{"3-2E21\tU+4FF1\t# <cjk>\t[2004]", 2004, 0},
+		{"3-2129\tU+003F\t# QUESTION MARK\tFullwidth: U+FF1F", 0, 0xFF1F},
+		{"3-2131\tU+203E\t# OVERLINE\tWindows: U+FFE3 [2000] Fullwidth: U+FFE3", 2000, 0xFFE3},
+		{"3-2121\t\t", 0, 0},
+	}
+	for _, tt := range convertedPairs {
+		year, fullwidth := parseComment(tt.in)
+		if year != tt.year || fullwidth != tt.fullwidth {
+			test.Errorf("parseComment(%q) got: %v, %X want: %v, %X", tt.in, year, fullwidth, tt.year, tt.fullwidth)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	data := []byte("3-2121\tU+3000\t# IDEOGRAPHIC SPACE\n")
+	sum := sha256Sum(data)
+	if len(sum) != 64 {
+		t.Errorf("sha256Sum got: %v", sum)
+	}
+	if err := Verify(data, sum+"  jisx0213-2004-std.txt\n"); err != nil {
+		t.Errorf("Verify error: %v", err)
+	}
+	if err := Verify(data, strings.ToUpper(sum)); err != nil {
+		t.Errorf("Verify error: %v", err)
+	}
+	if err := Verify(append(data, '\n'), sum); err == nil {
+		t.Errorf("Verify should be error")
+	}
+	if err := Verify(data, ""); err == nil {
+		t.Errorf("Verify should be error")
+	}
+}
+
+func TestSourceVersion(t *testing.T) {
+	data := []byte("## JIS X 0213:2004 vs Unicode mapping table\n" +
+		"## Date: 7 Mar 2009\n" +
+		"## Version: 1.0\n" +
+		"3-2121\tU+3000\t# IDEOGRAPHIC SPACE\n" +
+		"## Date: ignored\n")
+	if got, want := sourceVersion(data), "Date: 7 Mar 2009, Version: 1.0"; got != want {
+		t.Errorf("sourceVersion got: %v want: %v", got, want)
+	}
+	if got, want := sourceVersion([]byte("3-2121\tU+3000\n")), "unknown version"; got != want {
+		t.Errorf("sourceVersion got: %v want: %v", got, want)
+	}
+}
+
+func TestParseLine(test *testing.T) {
+	var convertedPairs = []struct {
+		in        string
+		m, k, t   int
+		uni, uni2 int32
+		isSuccess bool
+	}{
+		{"3-2121\tU+3000\t# IDEOGRAPHIC SPACE", 3, 0x21, 0x21, 0x3000, 0, true},
+		{"3-2477\tU+304B+309A\t# \t[2000]", 3, 0x24, 0x77, 0x304B, 0x309A, true},
+		{"4-2122\t\t# <reserved>", 4, 0x21, 0x22, 0, 0, true},
+		{"## comment", 0, 0, 0, 0, 0, false},
+	}
+	for _, tt := range convertedPairs {
+		m, k, t, uni, uni2 := 0, 0, 0, int32(0), int32(0)
+		err := parseLine(tt.in, &m, &k, &t, &uni, &uni2)
+		if (err == nil) != tt.isSuccess {
+			test.Errorf("parseLine(%q) error: %v", tt.in, err)
+		}
+		if tt.isSuccess && (m != tt.m || k != tt.k || t != tt.t || uni != tt.uni || uni2 != tt.uni2) {
+			test.Errorf("parseLine(%q) got: %v,%X,%X,%X,%X", tt.in, m, k, t, uni, uni2)
+		}
+	}
+}
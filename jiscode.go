@@ -0,0 +1,149 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// JISCode is a code point of JIS X 0213 with men (plane), ku (row) and ten (cell).
+// The zero value is not a valid code point.
+type JISCode struct {
+	men, ku, ten int8
+}
+
+var (
+	jisCodeKutenRe = regexp.MustCompile(`^(?:第([1-4])水準)?([12])-(\d{1,2})-(\d{1,2})$`)
+	jisCodeHexRe   = regexp.MustCompile(`^(?:0[xX]|([34])-)([0-9A-Fa-f]{4})$`)
+)
+
+// NewJISCode returns JISCode from men, ku and ten
+func NewJISCode(men, ku, ten int) (JISCode, error) {
+	if err := checkKuten(men, ku, ten); err != nil {
+		return JISCode{}, err
+	}
+	return JISCode{men: int8(men), ku: int8(ku), ten: int8(ten)}, nil
+}
+
+// ParseJISCode parses JIS code notations:
+// men-ku-ten ("1-85-54"), Aozora Bunko gaiji reference ("第3水準1-85-54"; the level must match),
+// JIS hex code of plane 1 ("0x7556") and x0213.org notation ("3-7556", "4-2121").
+func ParseJISCode(s string) (JISCode, error) {
+	if m := jisCodeKutenRe.FindStringSubmatch(s); m != nil {
+		men, _ := strconv.Atoi(m[2])
+		ku, _ := strconv.Atoi(m[3])
+		ten, _ := strconv.Atoi(m[4])
+		if m[1] != "" {
+			if level, _ := strconv.Atoi(m[1]); level != KanjiLevel(men, ku, ten) {
+				return JISCode{}, fmt.Errorf("JIS code %q is not 第%d水準", s, level)
+			}
+		}
+		return NewJISCode(men, ku, ten)
+	}
+	if m := jisCodeHexRe.FindStringSubmatch(s); m != nil {
+		men := 1
+		if m[1] == "4" {
+			men = 2
+		}
+		v, _ := strconv.ParseUint(m[2], 16, 16)
+		return NewJISCode(men, int(v>>8)-0x20, int(v&0xff)-0x20)
+	}
+	return JISCode{}, fmt.Errorf("could not parse JIS code %q", s)
+}
+
+// JISCodeFromSjis returns JISCode from Shift_JIS-2004 bytes (2 bytes)
+func JISCodeFromSjis(b []byte) (JISCode, error) {
+	men, ku, ten, err := Sjis2Kuten(b)
+	if err != nil {
+		return JISCode{}, err
+	}
+	return NewJISCode(men, ku, ten)
+}
+
+// JISCodeFromEUC returns JISCode from EUC-JIS-2004 bytes
+// (2 bytes, or 3 bytes with 0x8F prefix for plane 2)
+func JISCodeFromEUC(b []byte) (JISCode, error) {
+	men, ku, ten, err := Euc2Kuten(b)
+	if err != nil {
+		return JISCode{}, err
+	}
+	return NewJISCode(men, ku, ten)
+}
+
+// Men returns men (plane) of the code; 0 if the code is not valid
+func (c JISCode) Men() int { return int(c.men) }
+
+// Ku returns ku (row) of the code
+func (c JISCode) Ku() int { return int(c.ku) }
+
+// Ten returns ten (cell) of the code
+func (c JISCode) Ten() int { return int(c.ten) }
+
+// IsValid checks the code is in the range of JIS X 0213
+func (c JISCode) IsValid() bool {
+	_, err := NewJISCode(c.Men(), c.Ku(), c.Ten())
+	return err == nil
+}
+
+// String returns men-ku-ten notation like "1-85-54"
+func (c JISCode) String() string {
+	return fmt.Sprintf("%d-%d-%d", c.men, c.ku, c.ten)
+}
+
+// Ref returns Aozora Bunko gaiji reference like "第3水準1-85-54"
+func (c JISCode) Ref() string {
+	return JisRef(c.Men(), c.Ku(), c.Ten())
+}
+
+// JIS returns 2 bytes JIS code in the plane like 0x7556
+func (c JISCode) JIS() uint16 {
+	return uint16(c.ku+0x20)<<8 | uint16(c.ten+0x20)
+}
+
+// Hex returns JIS hex code notation like "0x7556"
+func (c JISCode) Hex() string {
+	return fmt.Sprintf("0x%04X", c.JIS())
+}
+
+// X0213 returns x0213.org notation like "3-7556" (plane 1) or "4-2121" (plane 2)
+func (c JISCode) X0213() string {
+	return fmt.Sprintf("%d-%04X", c.men+2, c.JIS())
+}
+
+// Sjis returns Shift_JIS-2004 bytes of the code
+func (c JISCode) Sjis() ([]byte, error) {
+	return Kuten2Sjis2004(c.Men(), c.Ku(), c.Ten())
+}
+
+// EUC returns EUC-JIS-2004 bytes of the code
+func (c JISCode) EUC() ([]byte, error) {
+	return Kuten2Euc(c.Men(), c.Ku(), c.Ten())
+}
+
+// Iso2022 returns ISO-2022-JP-2004 byte pair of the code (without escape sequence)
+func (c JISCode) Iso2022() ([]byte, error) {
+	return Kuten2Iso2022(c.Men(), c.Ku(), c.Ten())
+}
+
+// Unicode returns the character of the code
+func (c JISCode) Unicode() (string, error) {
+	return Jis2Uni(c.Men(), c.Ku(), c.Ten())
+}
+
+// MarshalText implements encoding.TextMarshaler with men-ku-ten notation
+func (c JISCode) MarshalText() ([]byte, error) {
+	if !c.IsValid() {
+		return nil, fmt.Errorf("invalid JIS code: %v", c)
+	}
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with the notations of ParseJISCode
+func (c *JISCode) UnmarshalText(text []byte) error {
+	code, err := ParseJISCode(string(text))
+	if err != nil {
+		return err
+	}
+	*c = code
+	return nil
+}
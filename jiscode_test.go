@@ -0,0 +1,168 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseJISCode(t *testing.T) {
+	var convertedPairs = []struct {
+		in        string
+		out       JISCode
+		isSuccess bool
+	}{
+		{"1-85-54", JISCode{1, 85, 54}, true},
+		{"第3水準1-85-54", JISCode{1, 85, 54}, true},
+		{"第4水準2-1-1", JISCode{2, 1, 1}, true},
+		{"0x7556", JISCode{1, 85, 54}, true},
+		{"0X3021", JISCode{1, 16, 1}, true},
+		{"3-7556", JISCode{1, 85, 54}, true},
+		{"4-2121", JISCode{2, 1, 1}, true},
+		{"第3水準2-1-1", JISCode{}, false},
+		{"第4水準1-85-54", JISCode{}, false},
+		{"第1水準1-13-21", JISCode{}, false},
+		{"3-1-1", JISCode{}, false},
+		{"1-95-1", JISCode{}, false},
+		{"1-0-1", JISCode{}, false},
+		{"0x2020", JISCode{}, false},
+		{"0x7F21", JISCode{}, false},
+		{"U+4E9C", JISCode{}, false},
+		{"", JISCode{}, false},
+	}
+	for _, tt := range convertedPairs {
+		got, err := ParseJISCode(tt.in)
+		if (err == nil) != tt.isSuccess {
+			t.Errorf("ParseJISCode(%q) error: %v", tt.in, err)
+		}
+		if got != tt.out {
+			t.Errorf("ParseJISCode(%q) got: %v want: %v", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestJISCodeFormat(t *testing.T) {
+	var convertedPairs = []struct {
+		code             JISCode
+		str, ref, hex, x string
+		sjis, euc        []byte
+	}{
+		{JISCode{1, 16, 1}, "1-16-1", "第1水準1-16-1", "0x3021", "3-3021", []byte{0x88, 0x9F}, []byte{0xB0, 0xA1}},
+		{JISCode{1, 85, 54}, "1-85-54", "第3水準1-85-54", "0x7556", "3-7556", []byte{0xEB, 0x75}, []byte{0xF5, 0xD6}},
+		{JISCode{1, 13, 1}, "1-13-1", "1-13-1", "0x2D21", "3-2D21", []byte{0x87, 0x40}, []byte{0xAD, 0xA1}},
+		{JISCode{2, 1, 1}, "2-1-1", "第4水準2-1-1", "0x2121", "4-2121", []byte{0xF0, 0x40}, []byte{0x8F, 0xA1, 0xA1}},
+		{JISCode{2, 8, 1}, "2-8-1", "第4水準2-8-1", "0x2821", "4-2821", []byte{0xF0, 0x9F}, []byte{0x8F, 0xA8, 0xA1}},
+		{JISCode{2, 78, 1}, "2-78-1", "第4水準2-78-1", "0x6E21", "4-6E21", []byte{0xF4, 0x9F}, []byte{0x8F, 0xEE, 0xA1}},
+		{JISCode{2, 94, 86}, "2-94-86", "第4水準2-94-86", "0x7E76", "4-7E76", []byte{0xFC, 0xF4}, []byte{0x8F, 0xFE, 0xF6}},
+	}
+	for _, tt := range convertedPairs {
+		if got := tt.code.String(); got != tt.str {
+			t.Errorf("JISCode.String got: %v want: %v", got, tt.str)
+		}
+		if got := tt.code.Ref(); got != tt.ref {
+			t.Errorf("JISCode.Ref got: %v want: %v", got, tt.ref)
+		}
+		if got := tt.code.Hex(); got != tt.hex {
+			t.Errorf("JISCode.Hex got: %v want: %v", got, tt.hex)
+		}
+		if got := tt.code.X0213(); got != tt.x {
+			t.Errorf("JISCode.X0213 got: %v want: %v", got, tt.x)
+		}
+		if got, err := tt.code.Sjis(); err != nil || !bytes.Equal(got, tt.sjis) {
+			t.Errorf("JISCode.Sjis got: % X want: % X (%v)", got, tt.sjis, err)
+		}
+		if got, err := JISCodeFromSjis(tt.sjis); err != nil || got != tt.code {
+			t.Errorf("JISCodeFromSjis got: %v want: %v (%v)", got, tt.code, err)
+		}
+		if got, err := tt.code.EUC(); err != nil || !bytes.Equal(got, tt.euc) {
+			t.Errorf("JISCode.EUC got: % X want: % X (%v)", got, tt.euc, err)
+		}
+		if got, err := JISCodeFromEUC(tt.euc); err != nil || got != tt.code {
+			t.Errorf("JISCodeFromEUC got: %v want: %v (%v)", got, tt.code, err)
+		}
+		if got, err := ParseJISCode(tt.x); err != nil || got != tt.code {
+			t.Errorf("ParseJISCode(%v) got: %v want: %v (%v)", tt.x, got, tt.code, err)
+		}
+	}
+	if _, err := (JISCode{}).Sjis(); err == nil {
+		t.Errorf("JISCode{}.Sjis should be error")
+	}
+	if _, err := (JISCode{2, 2, 1}).Sjis(); err == nil {
+		t.Errorf("JISCode{2, 2, 1}.Sjis should be error")
+	}
+}
+
+func TestJISCodeAccessors(t *testing.T) {
+	code, err := Uni2Jis("あ")
+	if err != nil {
+		t.Fatalf("Uni2Jis error: %v", err)
+	}
+	if code.Men() != 1 || code.Ku() != 4 || code.Ten() != 2 || !code.IsValid() {
+		t.Errorf("JISCode got: %v, %v, %v", code.Men(), code.Ku(), code.Ten())
+	}
+	if got, err := code.Unicode(); err != nil || got != "あ" {
+		t.Errorf("JISCode.Unicode got: %v (%v)", got, err)
+	}
+	if (JISCode{}).IsValid() {
+		t.Errorf("JISCode{} should not be valid")
+	}
+	if _, err := NewJISCode(3, 1, 1); err == nil {
+		t.Errorf("NewJISCode(3, 1, 1) should be error")
+	}
+}
+
+func TestJISCodeJSON(t *testing.T) {
+	type entry struct {
+		Char string  `json:"char"`
+		Code JISCode `json:"code"`
+	}
+	b, err := json.Marshal(entry{"𠂉", JISCode{2, 1, 1}})
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	if got, want := string(b), `{"char":"𠂉","code":"2-1-1"}`; got != want {
+		t.Errorf("json.Marshal got: %v want: %v", got, want)
+	}
+
+	var e entry
+	if err := json.Unmarshal([]byte(`{"char":"亜","code":"第1水準1-16-1"}`), &e); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if e.Code != (JISCode{1, 16, 1}) {
+		t.Errorf("json.Unmarshal got: %v", e.Code)
+	}
+	if err := json.Unmarshal([]byte(`{"code":"9-9-9"}`), &e); err == nil {
+		t.Errorf("json.Unmarshal should be error")
+	}
+	if _, err := json.Marshal(JISCode{}); err == nil {
+		t.Errorf("json.Marshal(JISCode{}) should be error")
+	}
+}
+
+func TestAllJISCodeSjis(t *testing.T) {
+	for men := 1; men <= 2; men++ {
+		for ku := 1; ku <= 94; ku++ {
+			for ten := 1; ten <= 94; ten++ {
+				code := JISCode{int8(men), int8(ku), int8(ten)}
+				if euc, err := code.EUC(); err != nil {
+					t.Errorf("JISCode.EUC %v: %v", code, err)
+				} else if got, err := JISCodeFromEUC(euc); got != code {
+					t.Errorf("JISCodeFromEUC got: %v want: %v (%v)", got, code, err)
+				}
+				sjis, err := code.Sjis()
+				if err != nil {
+					if decodeJis(men, ku, ten) != "" {
+						t.Errorf("JISCode.Sjis %v: %v", code, err)
+					}
+					continue
+				}
+				if got, err := JISCodeFromSjis(sjis); got != code {
+					t.Errorf("JISCodeFromSjis % X got: %v want: %v (%v)", sjis, got, code, err)
+				}
+				if men == 1 && !bytes.Equal(sjis, Kuten2Sjis(ku, ten)) {
+					t.Errorf("JISCode.Sjis %v got: % X want: % X", code, sjis, Kuten2Sjis(ku, ten))
+				}
+			}
+		}
+	}
+}
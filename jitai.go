@@ -0,0 +1,160 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Jitai is a form of kanji (字体)
+type Jitai int
+
+// Forms of kanji
+const (
+	// JitaiPreserve keeps kanji as is
+	JitaiPreserve Jitai = iota
+	// Shinjitai is the new form (新字体) like `国`
+	Shinjitai
+	// Kyujitai is the old form (旧字体) like `國`
+	Kyujitai
+)
+
+// String returns the name of the form
+func (j Jitai) String() string {
+	switch j {
+	case Shinjitai:
+		return "shinjitai"
+	case Kyujitai:
+		return "kyujitai"
+	}
+	return "preserve"
+}
+
+// ParseJitai parses the name of the form ("shin" or "shinjitai", "kyu" or "kyujitai")
+func ParseJitai(s string) (Jitai, error) {
+	switch strings.ToLower(s) {
+	case "", "preserve":
+		return JitaiPreserve, nil
+	case "shin", "shinjitai":
+		return Shinjitai, nil
+	case "kyu", "kyujitai":
+		return Kyujitai, nil
+	}
+	return JitaiPreserve, fmt.Errorf("unknown jitai: %s", s)
+}
+
+// jitaiPairs are pairs of kyujitai and shinjitai, converted in both directions
+const jitaiPairs = "" +
+	"亞亜惡悪壓圧圍囲醫医爲為壹壱隱隠營営榮栄衞衛驛駅圓円鹽塩應応歐欧毆殴櫻桜" +
+	"假仮價価畫画會会壞壊懷懐繪絵擴拡殼殻覺覚學学嶽岳樂楽勸勧卷巻歡歓觀観" +
+	"關関陷陥巖巌顏顔歸帰氣気龜亀僞偽戲戯犧犠舊旧據拠擧挙峽峡挾挟狹狭曉暁區区" +
+	"驅駆勳勲徑径惠恵溪渓經経繼継莖茎螢蛍輕軽鷄鶏儉倹劍剣圈圏檢検權権獻献縣県" +
+	"險険顯顕驗験嚴厳效効廣広恆恒鑛鉱號号國国濟済碎砕齋斎劑剤雜雑參参慘惨棧桟" +
+	"蠶蚕贊賛殘残齒歯兒児辭辞濕湿實実舍舎寫写釋釈壽寿收収從従澁渋獸獣縱縦" +
+	"肅粛處処緖緒敍叙將将燒焼稱称證証乘乗剩剰壤壌孃嬢條条淨浄疊畳穰穣讓譲釀醸" +
+	"觸触寢寝愼慎眞真盡尽圖図粹粋醉酔隨随髓髄數数樞枢聲声靜静齊斉攝摂竊窃專専" +
+	"戰戦淺浅潛潜纖繊踐践錢銭禪禅雙双壯壮搜捜插挿爭争總総聰聡莊荘裝装騷騒臟臓" +
+	"藏蔵屬属續続墮堕體体對対帶帯滯滞瀧滝擇択澤沢單単擔担膽胆團団彈弾斷断癡痴" +
+	"遲遅晝昼鑄鋳廳庁聽聴鎭鎮遞逓鐵鉄轉転點点傳伝黨党盜盗當当鬪闘獨独" +
+	"讀読屆届繩縄貳弐惱悩腦脳廢廃拜拝賣売麥麦發発髮髪拔抜蠻蛮祕秘濱浜甁瓶拂払" +
+	"佛仏竝並變変邊辺舖舗穗穂寶宝豐豊沒没飜翻每毎滿満默黙彌弥譯訳藥薬" +
+	"譽誉搖揺樣様謠謡來来賴頼亂乱覽覧龍竜兩両獵猟綠緑壘塁勵励禮礼隸隷靈霊齡齢" +
+	"戀恋爐炉勞労樓楼祿禄錄録灣湾德徳卽即槪概靑青鄕郷"
+
+// jitaiOldOnly are pairs of kyujitai and shinjitai converted only into shinjitai,
+// because the shinjitai is also a different character or has several kyujitai
+const jitaiOldOnly = "辨弁瓣弁辯弁缺欠藝芸臺台豫予餘余絲糸蟲虫罐缶燈灯萬万與与"
+
+var (
+	kyuToShin = map[rune]rune{}
+	shinToKyu = map[rune]rune{}
+
+	// jitaiSkipRe matches ruby and annotations, which are not converted
+	// except the targets of reference annotations like `［＃「国」に傍点］`
+	jitaiSkipRe = regexp.MustCompile(`《[^》]*》|［＃[^］]*］`)
+)
+
+func init() {
+	r := []rune(jitaiPairs)
+	for i := 0; i+1 < len(r); i += 2 {
+		kyuToShin[r[i]] = r[i+1]
+		shinToKyu[r[i+1]] = r[i]
+	}
+	r = []rune(jitaiOldOnly)
+	for i := 0; i+1 < len(r); i += 2 {
+		kyuToShin[r[i]] = r[i+1]
+	}
+}
+
+// ToShinjitai converts kyujitai in str into shinjitai.
+// Ruby (`《…》`) and annotations (`［＃…］`) are left as is, except the targets
+// of annotations like `［＃「國」に傍点］`, which follow the text.
+func ToShinjitai(str string) string {
+	return convJitai(str, Shinjitai, false)
+}
+
+// ToKyujitai converts shinjitai in str into kyujitai.
+// Ruby (`《…》`) and annotations (`［＃…］`) are left as is, except the targets
+// of annotations like `［＃「国」に傍点］`, which follow the text.
+func ToKyujitai(str string) string {
+	return convJitai(str, Kyujitai, false)
+}
+
+// convJitai converts str into the form j. If annotate is true, converted
+// characters not in JIS X 0208 are written as gaiji annotations.
+func convJitai(str string, j Jitai, annotate bool) string {
+	if j == JitaiPreserve {
+		return str
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range jitaiSkipRe.FindAllStringIndex(str, -1) {
+		b.WriteString(convJitaiText(str[last:loc[0]], j, annotate))
+		skip := str[loc[0]:loc[1]]
+		if strings.HasPrefix(skip, "［＃「") {
+			if i := strings.Index(skip, "」"); i > 0 {
+				target := skip[len("［＃「"):i]
+				skip = "［＃「" + convJitaiText(target, j, annotate) + skip[i:]
+			}
+		}
+		b.WriteString(skip)
+		last = loc[1]
+	}
+	b.WriteString(convJitaiText(str[last:], j, annotate))
+	return b.String()
+}
+
+func convJitaiText(str string, j Jitai, annotate bool) string {
+	table := kyuToShin
+	if j == Kyujitai {
+		table = shinToKyu
+	}
+	var b strings.Builder
+	for _, r := range str {
+		to, ok := table[r]
+		switch {
+		case !ok:
+			b.WriteRune(r)
+		case annotate && !in0208(string(to)):
+			b.WriteString(jitaiAnnotation(r, to, j))
+		default:
+			b.WriteRune(to)
+		}
+	}
+	return b.String()
+}
+
+// jitaiAnnotation returns gaiji annotation of to, converted from r into the form j
+func jitaiAnnotation(r, to rune, j Jitai) string {
+	desc := "旧字"
+	if j == Shinjitai {
+		desc = "新字"
+	}
+	if in0208(string(r)) {
+		desc = fmt.Sprintf("「%c」の%s", r, desc)
+	}
+	if jis, err := Uni2Jis(string(to)); err == nil {
+		return fmt.Sprintf("※［＃%s、%s］", desc, jis.Ref())
+	}
+	return fmt.Sprintf("※［＃%s、U+%04X］", desc, to)
+}
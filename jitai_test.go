@@ -0,0 +1,103 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJitaiTable(t *testing.T) {
+	seen := map[rune]bool{}
+	for _, s := range []string{jitaiPairs, jitaiOldOnly} {
+		r := []rune(s)
+		if len(r)%2 != 0 {
+			t.Fatalf("odd length of jitai table")
+		}
+		for i := 0; i < len(r); i += 2 {
+			if seen[r[i]] {
+				t.Errorf("kyujitai %c is duplicated", r[i])
+			}
+			seen[r[i]] = true
+			if !in0208(string(r[i+1])) {
+				t.Errorf("shinjitai %c of %c is not in JIS X 0208", r[i+1], r[i])
+			}
+		}
+	}
+	for shin, kyu := range shinToKyu {
+		if kyuToShin[kyu] != shin {
+			t.Errorf("%c -> %c is not reversible", shin, kyu)
+		}
+	}
+}
+
+func TestToShinjitai(t *testing.T) {
+	var convertedStrings = []struct {
+		in, out string
+	}{
+		{"學校の國語", "学校の国語"},
+		{"廣い辯論", "広い弁論"},
+		{"｜國《くに》", "｜国《くに》"},
+		{"國《國》［＃「國」に傍点］", "国《國》［＃「国」に傍点］"},
+		{"蟲と燈", "虫と灯"},
+		{"德", "徳"},
+	}
+	for _, tt := range convertedStrings {
+		if got := ToShinjitai(tt.in); got != tt.out {
+			t.Errorf("ToShinjitai(%q) got: %q want: %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestToKyujitai(t *testing.T) {
+	var convertedStrings = []struct {
+		in, out string
+	}{
+		{"学校の国語", "學校の國語"},
+		{"弁当", "弁當"},
+		{"国《くに》［＃「国」に傍点］", "國《くに》［＃「國」に傍点］"},
+		{"国の学校《がっこう》［＃「学」に傍点］", "國の學校《がっこう》［＃「學」に傍点］"},
+		{"［＃ここから２字下げ］", "［＃ここから２字下げ］"},
+		{"糸と虫と缶と灯と万と与える", "糸と虫と缶と灯と万と与える"},
+		{"徳", "德"},
+	}
+	for _, tt := range convertedStrings {
+		if got := ToKyujitai(tt.in); got != tt.out {
+			t.Errorf("ToKyujitai(%q) got: %q want: %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestConvJitaiAnnotate(t *testing.T) {
+	var convertedStrings = []struct {
+		in  string
+		j   Jitai
+		out string
+	}{
+		{"道徳と国", Kyujitai, "道※［＃「徳」の旧字、第3水準1-84-37］と國"},
+		{"道德", Shinjitai, "道徳"},
+		{"国", JitaiPreserve, "国"},
+	}
+	for _, tt := range convertedStrings {
+		if got := convJitai(tt.in, tt.j, true); got != tt.out {
+			t.Errorf("convJitai(%q, %v) got: %q want: %q", tt.in, tt.j, got, tt.out)
+		}
+	}
+}
+
+func TestEncodeWithJitai(t *testing.T) {
+	output := new(bytes.Buffer)
+	if err := Encode(strings.NewReader("道徳の学校《がっこう》"), output, WithJitai(Kyujitai)); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("道※［＃「徳」の旧字、第3水準1-84-37］の學校《がっこう》"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+
+	decoded := new(bytes.Buffer)
+	if err := Decode(output, decoded, WithJitai(Shinjitai)); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got, want := decoded.String(), "道※［＃「徳」の旧字、第3水準1-84-37］の学校《がっこう》"; got != want {
+		t.Errorf("Decode got: %v want: %v", got, want)
+	}
+}
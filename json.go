@@ -0,0 +1,370 @@
+package aozoraconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The JSON schema of Document:
+//
+//	{
+//	  "title": "吾輩は猫である",
+//	  "author": "夏目漱石",
+//	  "blocks": [
+//	    {"type": "heading", "level": 1, "inlines": [{"type": "text", "text": "一"}]},
+//	    {"type": "paragraph", "inlines": [
+//	      {"type": "ruby", "text": "吾輩", "ruby": "わがはい"},
+//	      {"type": "emphasis", "style": "傍点", "children": [{"type": "text", "text": "じめじめ"}]},
+//	      {"type": "gaiji", "text": "挘", "annotation": "「てへん＋劣」、第3水準1-84-77",
+//	       "jis": "1-84-77", "unicode": "U+6318"}
+//	    ]},
+//	    {"type": "indent", "level": 2, "blocks": [...]},
+//	    {"type": "page_break"}
+//	  ]
+//	}
+//
+// Types of blocks are paragraph, heading (level 1-3), page_break, indent (level is
+// the width) and bottom (level 0 is 地付き, N is 地からN字上げ). Types of inlines are
+// text, ruby, emphasis, tate_chu_yoko, gaiji and note. The character of gaiji is read
+// from text, jis or unicode in this order.
+
+var (
+	blockKindNames  = []string{"paragraph", "heading", "page_break", "indent", "bottom"}
+	inlineKindNames = []string{"text", "ruby", "emphasis", "tate_chu_yoko", "gaiji", "note"}
+)
+
+// MarshalText implements encoding.TextMarshaler with the name of the kind
+func (k BlockKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(blockKindNames) {
+		return nil, fmt.Errorf("invalid block kind: %d", k)
+	}
+	return []byte(blockKindNames[k]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with the name of the kind
+func (k *BlockKind) UnmarshalText(text []byte) error {
+	for i, name := range blockKindNames {
+		if name == string(text) {
+			*k = BlockKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown block type: %s", text)
+}
+
+// MarshalText implements encoding.TextMarshaler with the name of the kind
+func (k InlineKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(inlineKindNames) {
+		return nil, fmt.Errorf("invalid inline kind: %d", k)
+	}
+	return []byte(inlineKindNames[k]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with the name of the kind
+func (k *InlineKind) UnmarshalText(text []byte) error {
+	for i, name := range inlineKindNames {
+		if name == string(text) {
+			*k = InlineKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown inline type: %s", text)
+}
+
+// jsonDocument, jsonBlock and jsonInline are the JSON representation of Document
+type jsonDocument struct {
+	Title  string      `json:"title"`
+	Author string      `json:"author"`
+	Blocks []jsonBlock `json:"blocks"`
+}
+
+type jsonBlock struct {
+	Type    BlockKind    `json:"type"`
+	Level   int          `json:"level,omitempty"`
+	Inlines []jsonInline `json:"inlines,omitempty"`
+	Blocks  []jsonBlock  `json:"blocks,omitempty"`
+}
+
+type jsonInline struct {
+	Type       InlineKind   `json:"type"`
+	Text       string       `json:"text,omitempty"`
+	Ruby       string       `json:"ruby,omitempty"`
+	Style      string       `json:"style,omitempty"`
+	Annotation string       `json:"annotation,omitempty"`
+	JIS        *JISCode     `json:"jis,omitempty"`     // men-ku-ten of gaiji
+	Unicode    string       `json:"unicode,omitempty"` // code points of gaiji like "U+6318"
+	Children   []jsonInline `json:"children,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler with the schema above
+func (d *Document) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonDocument{d.Title, d.Author, toJSONBlocks(d.Blocks)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler with the schema above
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var j jsonDocument
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	blocks, err := fromJSONBlocks(j.Blocks)
+	if err != nil {
+		return err
+	}
+	*d = Document{Title: j.Title, Author: j.Author, Blocks: blocks}
+	return nil
+}
+
+func toJSONBlocks(blocks []Block) []jsonBlock {
+	var ret []jsonBlock
+	for _, b := range blocks {
+		ret = append(ret, jsonBlock{b.Kind, b.Level, toJSONInlines(b.Inlines), toJSONBlocks(b.Blocks)})
+	}
+	return ret
+}
+
+func toJSONInlines(inlines []Inline) []jsonInline {
+	var ret []jsonInline
+	for _, in := range inlines {
+		j := jsonInline{
+			Type:       in.Kind,
+			Text:       in.Text,
+			Ruby:       in.Ruby,
+			Style:      in.Style,
+			Annotation: in.Annotation,
+			Children:   toJSONInlines(in.Children),
+		}
+		if in.Kind == InlineGaiji {
+			if in.Code.IsValid() {
+				code := in.Code
+				j.JIS = &code
+			}
+			var u []string
+			for _, r := range in.Text {
+				u = append(u, fmt.Sprintf("U+%04X", r))
+			}
+			j.Unicode = strings.Join(u, " ")
+		}
+		ret = append(ret, j)
+	}
+	return ret
+}
+
+func fromJSONBlocks(blocks []jsonBlock) ([]Block, error) {
+	var ret []Block
+	for _, j := range blocks {
+		inlines, err := fromJSONInlines(j.Inlines)
+		if err != nil {
+			return nil, err
+		}
+		children, err := fromJSONBlocks(j.Blocks)
+		if err != nil {
+			return nil, err
+		}
+		if j.Type == BlockHeading && (j.Level < 1 || j.Level > 3) {
+			return nil, fmt.Errorf("invalid heading level: %d", j.Level)
+		}
+		ret = append(ret, Block{Kind: j.Type, Level: j.Level, Inlines: inlines, Blocks: children})
+	}
+	return ret, nil
+}
+
+func fromJSONInlines(inlines []jsonInline) ([]Inline, error) {
+	var ret []Inline
+	for _, j := range inlines {
+		children, err := fromJSONInlines(j.Children)
+		if err != nil {
+			return nil, err
+		}
+		in := Inline{
+			Kind:       j.Type,
+			Text:       j.Text,
+			Ruby:       j.Ruby,
+			Style:      j.Style,
+			Annotation: j.Annotation,
+			Children:   children,
+		}
+		if j.JIS != nil {
+			in.Code = *j.JIS
+			if in.Text == "" {
+				in.Text, _ = in.Code.Unicode()
+			}
+		}
+		if in.Text == "" && j.Unicode != "" {
+			if in.Text, err = parseCodePoints(j.Unicode); err != nil {
+				return nil, err
+			}
+		}
+		if in.Kind == InlineEmphasis {
+			if _, _, ok := rangeStyle(in.Style); !ok {
+				return nil, fmt.Errorf("unknown emphasis style: %s", in.Style)
+			}
+		}
+		ret = append(ret, in)
+	}
+	return ret, nil
+}
+
+// parseCodePoints parses code points like "U+6318" separated by spaces
+func parseCodePoints(s string) (string, error) {
+	var ret []rune
+	for _, f := range strings.Fields(s) {
+		v, err := strconv.ParseUint(strings.TrimPrefix(f, "U+"), 16, 32)
+		if err != nil || !strings.HasPrefix(f, "U+") || !utf8.ValidRune(rune(v)) {
+			return "", fmt.Errorf("invalid code point: %s", f)
+		}
+		ret = append(ret, rune(v))
+	}
+	return string(ret), nil
+}
+
+// WriteJSON writes doc as JSON
+func WriteJSON(w io.Writer, doc *Document) error {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "  ")
+	return e.Encode(doc)
+}
+
+// ReadJSON reads Document from JSON
+func ReadJSON(r io.Reader) (*Document, error) {
+	doc := &Document{}
+	if err := json.NewDecoder(r).Decode(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// FormatDocument returns doc in Aozora Bunko format (Unicode), which ParseDocument
+// parses into the same Document. The format cannot have an author without a title,
+// nor a title or an author of several lines.
+func FormatDocument(doc *Document) (string, error) {
+	if doc.Title == "" && doc.Author != "" {
+		return "", fmt.Errorf("document has an author but no title")
+	}
+	if strings.Contains(doc.Title, "\n") || strings.Contains(doc.Author, "\n") {
+		return "", fmt.Errorf("title or author has a line break")
+	}
+	var b strings.Builder
+	// the empty line ends the title and the author, even if they are empty
+	if doc.Title != "" {
+		b.WriteString(doc.Title + "\n")
+		if doc.Author != "" {
+			b.WriteString(doc.Author + "\n")
+		}
+	}
+	b.WriteString("\n")
+	formatBlocks(&b, doc.Blocks)
+	return b.String(), nil
+}
+
+func formatBlocks(b *strings.Builder, blocks []Block) {
+	for _, bl := range blocks {
+		switch bl.Kind {
+		case BlockPageBreak:
+			b.WriteString("［＃改ページ］\n")
+		case BlockIndent, BlockBottom:
+			n := toFullwidthDigits(fmt.Sprint(bl.Level))
+			single, start, end := "［＃"+n+"字下げ］", "［＃ここから"+n+"字下げ］", "［＃ここで字下げ終わり］"
+			if bl.Kind == BlockBottom && bl.Level == 0 {
+				single, start, end = "［＃地付き］", "［＃ここから地付き］", "［＃ここで地付き終わり］"
+			} else if bl.Kind == BlockBottom {
+				single, start, end = "［＃地から"+n+"字上げ］", "［＃ここから地から"+n+"字上げ］", "［＃ここで字上げ終わり］"
+			}
+			if len(bl.Blocks) == 1 && (bl.Blocks[0].Kind == BlockParagraph || bl.Blocks[0].Kind == BlockHeading) && len(bl.Blocks[0].Inlines) > 0 {
+				b.WriteString(single)
+				formatBlocks(b, bl.Blocks)
+				continue
+			}
+			b.WriteString(start + "\n")
+			formatBlocks(b, bl.Blocks)
+			b.WriteString(end + "\n")
+		default:
+			line := formatInlines(bl.Inlines, "")
+			if bl.Kind == BlockHeading {
+				for name, level := range midashiLevels {
+					if level == bl.Level {
+						line += "［＃「" + formatTarget(bl.Inlines) + "」は" + name + "］"
+					}
+				}
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+}
+
+// textEscaper escapes characters in text which would be read as the notation;
+// ParseDocument reads them back with notationEscapes
+var textEscaper = strings.NewReplacer(
+	"［＃", "※［＃始め角括弧、1-1-46］＃",
+	"《", "※［＃始め二重山括弧、1-1-52］",
+	"》", "※［＃終わり二重山括弧、1-1-53］",
+	"｜", "※［＃縦線、1-1-35］",
+)
+
+// formatInlines returns inlines in Aozora Bunko format; prev is the text before them
+func formatInlines(inlines []Inline, prev string) string {
+	var b strings.Builder
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineText:
+			b.WriteString(textEscaper.Replace(in.Text))
+		case InlineRuby:
+			if needsRubyBar(in.Text, prev+b.String()) {
+				b.WriteString("｜")
+			}
+			b.WriteString(in.Text + "《" + in.Ruby + "》")
+		case InlineEmphasis, InlineTateChuYoko:
+			b.WriteString(formatInlines(in.Children, prev+b.String()))
+			if in.Kind == InlineEmphasis {
+				b.WriteString("［＃「" + formatTarget(in.Children) + "」に" + in.Style + "］")
+			} else {
+				b.WriteString("［＃「" + formatTarget(in.Children) + "」は縦中横］")
+			}
+		case InlineGaiji:
+			b.WriteString(formatGaiji(in))
+		case InlineNote:
+			b.WriteString("［＃" + in.Annotation + "］")
+		}
+	}
+	return b.String()
+}
+
+// formatTarget returns the target of annotations for inlines
+func formatTarget(inlines []Inline) string {
+	var b strings.Builder
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineText, InlineRuby:
+			b.WriteString(in.Text)
+		case InlineEmphasis, InlineTateChuYoko:
+			b.WriteString(formatTarget(in.Children))
+		case InlineGaiji:
+			b.WriteString(formatGaiji(in))
+		}
+	}
+	return b.String()
+}
+
+// formatGaiji returns gaiji annotation of in, made from the code or the character
+// if in has no annotation. The character itself is not the description because
+// it may not be in Shift_JIS.
+func formatGaiji(in Inline) string {
+	switch {
+	case in.Annotation != "":
+		return "※［＃" + in.Annotation + "］"
+	case in.Code.IsValid():
+		return "※［＃外字、" + in.Code.Ref() + "］"
+	case in.Text != "":
+		var u []string
+		for _, r := range in.Text {
+			u = append(u, fmt.Sprintf("U+%04X", r))
+		}
+		return "※［＃外字、" + strings.Join(u, "+") + "］"
+	}
+	return "※［＃外字］"
+}
@@ -0,0 +1,154 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDocumentJSON(t *testing.T) {
+	doc := ParseDocument(documentSample)
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, doc); err != nil {
+		t.Fatalf("WriteJSON error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "\n  \"title\": \"吾輩は猫である\",\n") {
+		t.Errorf("WriteJSON got:\n%s", buf.String())
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`{"title":"吾輩は猫である","author":"夏目漱石","blocks":[{"type":"indent","level":3,"blocks":[{"type":"heading","level":1,`,
+		`{"type":"ruby","text":"吾輩","ruby":"わがはい"}`,
+		`{"type":"emphasis","style":"傍点","children":[{"type":"text","text":"じめじめ"}]}`,
+		`{"type":"gaiji","text":"挘","annotation":"「てへん＋劣」、第3水準1-84-77","jis":"1-84-77","unicode":"U+6318"}`,
+		`{"type":"tate_chu_yoko","children":[{"type":"text","text":"３８"}]}`,
+		`{"type":"note","annotation":"不明な注記"}`,
+		`{"type":"page_break"}`,
+		`{"type":"bottom","blocks":[`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("json.Marshal got:\n%s\nwant containing: %s", got, want)
+		}
+	}
+	back, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSON error: %v", err)
+	}
+	if !reflect.DeepEqual(back, doc) {
+		t.Errorf("ReadJSON got: %+v want: %+v", back, doc)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want *Document
+		err  bool
+	}{
+		{`{"title": "t", "blocks": [{"type": "paragraph", "inlines": [{"type": "gaiji", "jis": "1-84-77"}]}]}`,
+			&Document{Title: "t", Blocks: []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineGaiji, Text: "挘", Code: JISCode{1, 84, 77}}}}}}, false},
+		{`{"blocks": [{"type": "paragraph", "inlines": [{"type": "gaiji", "unicode": "U+546D"}]}]}`,
+			&Document{Blocks: []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineGaiji, Text: "呭"}}}}}, false},
+		{`{"blocks": [{"type": "paragraph", "inlines": [{"type": "gaiji", "unicode": "546D"}]}]}`, nil, true},
+		{`{"blocks": [{"type": "section"}]}`, nil, true},
+		{`{"blocks": [{"type": "heading", "level": 4}]}`, nil, true},
+		{`{"blocks": [{"type": "paragraph", "inlines": [{"type": "emphasis", "style": "強調"}]}]}`, nil, true},
+		{`{"blocks": [{"type": "paragraph", "inlines": [{"type": "gaiji", "jis": "3-1-1"}]}]}`, nil, true},
+	}
+	for _, tc := range testcases {
+		got, err := ReadJSON(strings.NewReader(tc.in))
+		if tc.err {
+			if err == nil {
+				t.Errorf("ReadJSON(%s) got no error", tc.in)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ReadJSON(%s) got: %+v, %v want: %+v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestFormatDocument(t *testing.T) {
+	doc := ParseDocument(documentSample)
+	text, err := FormatDocument(doc)
+	if err != nil {
+		t.Fatalf("FormatDocument error: %v", err)
+	}
+	want := "吾輩は猫である\n夏目漱石\n\n" +
+		"［＃３字下げ］一［＃「一」は大見出し］\n" +
+		"　吾輩《わがはい》は｜猫である《ねこである》。\n" +
+		"じめじめ［＃「じめじめ」に傍点］した所で※［＃「てへん＋劣」、第3水準1-84-77］いた。\n" +
+		"［＃ここから２字下げ］\n" +
+		"明治３８［＃「３８」は縦中横］年\n" +
+		"強調［＃「強調」に傍線］［＃不明な注記］\n" +
+		"［＃ここで字下げ終わり］\n" +
+		"［＃改ページ］\n" +
+		"［＃地付き］夏目\n"
+	if text != want {
+		t.Errorf("FormatDocument got:\n%s\nwant:\n%s", text, want)
+	}
+	if back := ParseDocument(text); !reflect.DeepEqual(back, doc) {
+		t.Errorf("ParseDocument(FormatDocument) got: %+v want: %+v", back, doc)
+	}
+
+	testcases := []struct {
+		in   Inline
+		want string
+	}{
+		{Inline{Kind: InlineGaiji, Text: "挘", Code: JISCode{1, 84, 77}}, "※［＃外字、第3水準1-84-77］"},
+		{Inline{Kind: InlineGaiji, Text: "呭"}, "※［＃外字、U+546D］"},
+		{Inline{Kind: InlineRuby, Text: "漢字", Ruby: "かんじ"}, "漢字《かんじ》"},
+		{Inline{Kind: InlineRuby, Text: "ひら", Ruby: "かな"}, "｜ひら《かな》"},
+	}
+	for _, tc := range testcases {
+		if got := formatInlines([]Inline{tc.in}, ""); got != tc.want {
+			t.Errorf("formatInlines(%+v) got: %q want: %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFormatDocumentRoundTrip(t *testing.T) {
+	para := func(inlines ...Inline) Block {
+		return Block{Kind: BlockParagraph, Inlines: inlines}
+	}
+	text := func(s string) Inline {
+		return Inline{Kind: InlineText, Text: s}
+	}
+	testcases := []*Document{
+		{Blocks: []Block{para(text("一行目")), para(text("二行目"))}},
+		{Blocks: []Block{{Kind: BlockIndent, Level: 2, Blocks: []Block{para(text("一行目")), para(text("二行目"))}}}},
+		{Title: "題名", Blocks: []Block{para(text("本文"))}},
+		{Blocks: []Block{para(text("見よ［＃ここ］"))}},
+		{Blocks: []Block{para(text("漢字《かんじ》と｜仮名《かな》"))}},
+		{Blocks: []Block{para(text("a｜b"), Inline{Kind: InlineRuby, Text: "漢字", Ruby: "かんじ"})}},
+		{Blocks: []Block{para(text("a|b"), Inline{Kind: InlineRuby, Text: "漢字", Ruby: "かんじ"})}},
+		{Blocks: []Block{para(text("｜"))}},
+		{Title: "題名", Author: "作者", Blocks: []Block{para(text("本文"))}},
+	}
+	for _, doc := range testcases {
+		text, err := FormatDocument(doc)
+		if err != nil {
+			t.Errorf("FormatDocument(%+v) error: %v", doc, err)
+			continue
+		}
+		if back := ParseDocument(text); !reflect.DeepEqual(back, doc) {
+			t.Errorf("ParseDocument(%q) got: %+v want: %+v", text, back, doc)
+		}
+	}
+
+	for _, doc := range []*Document{
+		{Author: "作者", Blocks: []Block{para(text("本文"))}},
+		{Title: "題\n名"},
+	} {
+		if text, err := FormatDocument(doc); err == nil {
+			t.Errorf("FormatDocument(%+v) got: %q want error", doc, text)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package aozoraconv
+
+import (
+	"strings"
+)
+
+var (
+	// kunojiMap maps kunoji-ten (くの字点) in Aozora Bunko notation
+	// into JIS X 0213 1-2-19..21 (U+3033..U+3035)
+	kunojiMap = []string{
+		"／″＼", "〴〵",
+		"／＼", "〳〵",
+	}
+	kunojiReplacer     = strings.NewReplacer(kunojiMap...)
+	kunojiFoldReplacer = strings.NewReplacer(
+		"〳", "／",
+		"〴", "／″",
+		"〵", "＼",
+	)
+)
+
+// ExpandKunoji converts kunoji-ten `／＼` and `／″＼` into `〳〵` and `〴〵`
+func ExpandKunoji(str string) string {
+	return kunojiReplacer.Replace(str)
+}
+
+// FoldKunoji converts kunoji-ten `〳〵` and `〴〵` into `／＼` and `／″＼`
+func FoldKunoji(str string) string {
+	return kunojiFoldReplacer.Replace(str)
+}
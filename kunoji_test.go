@@ -0,0 +1,45 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExpandKunoji(t *testing.T) {
+	var convertedPairs = []struct {
+		in  string
+		out string
+	}{
+		{"いよいよ", "いよいよ"},
+		{"いよ／＼", "いよ〳〵"},
+		{"しみ／″＼", "しみ〴〵"},
+		{"／＼と／″＼", "〳〵と〴〵"},
+	}
+	for _, tt := range convertedPairs {
+		if got := ExpandKunoji(tt.in); got != tt.out {
+			t.Errorf("ExpandKunoji got: %v want: %v", got, tt.out)
+		}
+		if got := FoldKunoji(tt.out); got != tt.in {
+			t.Errorf("FoldKunoji got: %v want: %v", got, tt.in)
+		}
+	}
+}
+
+func TestEncodeWithKunoji(t *testing.T) {
+	output := new(bytes.Buffer)
+	if err := Encode(strings.NewReader("いよ〳〵しみ〴〵"), output, WithKunoji()); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("いよ／＼しみ／″＼"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+
+	decoded := new(bytes.Buffer)
+	if err := Decode(output, decoded, WithKunoji()); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got, want := decoded.String(), "いよ〳〵しみ〴〵"; got != want {
+		t.Errorf("Decode got: %v want: %v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// LaTeXOptions are options of WriteLaTeX
+type LaTeXOptions struct {
+	// UTF writes gaiji not in JIS X 0208 as `\UTF{XXXX}` of otf package
+	// instead of Unicode characters
+	UTF bool
+}
+
+// latexEmphasis are the commands of emphasis styles; 傍点 styles not listed are `\kenten`
+var latexEmphasis = map[string]string{
+	"傍線":   `\uline`,
+	"二重傍線": `\uuline`,
+	"鎖線":   `\dotuline`,
+	"破線":   `\dashuline`,
+	"波線":   `\uwave`,
+	"太字":   `\textbf`,
+	"斜体":   `\textit`,
+}
+
+// latexHeadings are the commands of heading levels
+var latexHeadings = map[int]string{1: `\section*`, 2: `\subsection*`, 3: `\subsubsection*`}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`#`, `\#`,
+	`%`, `\%`,
+	`_`, `\_`,
+	`^`, `\textasciicircum{}`,
+	`~`, `\textasciitilde{}`,
+)
+
+// latexPreamble is the preamble for vertical typesetting with jlreq class.
+// jisage and jiage are the environments of 字下げ and 地付き (地からN字上げ).
+const latexPreamble = `\documentclass[tate,book]{jlreq}
+\usepackage{pxrubrica}
+\usepackage[normalem]{ulem}
+%s\newenvironment{jisage}[1]{\begin{list}{}{\setlength{\leftmargin}{#1\zw}\setlength{\topsep}{0pt}\setlength{\parsep}{0pt}\setlength{\itemsep}{0pt}}\item\relax}{\end{list}}
+\newenvironment{jiage}[1]{\begin{list}{}{\setlength{\rightmargin}{#1\zw}\setlength{\topsep}{0pt}\setlength{\parsep}{0pt}\setlength{\itemsep}{0pt}}\item\relax\raggedleft}{\end{list}}
+`
+
+// WriteLaTeX writes doc as a LaTeX document for vertical typesetting (upLaTeX or LuaLaTeX with jlreq).
+// Ruby is `\ruby`, 傍点 is `\kenten`, 縦中横 is `\tatechuyoko` and 改ページ is `\newpage`.
+// Annotations not interpreted are written as comments.
+func WriteLaTeX(w io.Writer, doc *Document, opts LaTeXOptions) error {
+	var b strings.Builder
+	otf := ""
+	if opts.UTF {
+		otf = "\\usepackage{otf}\n"
+	}
+	fmt.Fprintf(&b, latexPreamble, otf)
+	if doc.Title != "" {
+		fmt.Fprintf(&b, "\\title{%s}\n\\author{%s}\n\\date{}\n", latexEscaper.Replace(doc.Title), latexEscaper.Replace(doc.Author))
+	}
+	b.WriteString("\\begin{document}\n")
+	if doc.Title != "" {
+		b.WriteString("\\maketitle\n")
+	}
+	latexBlocks(&b, doc.Blocks, opts)
+	b.WriteString("\\end{document}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func latexBlocks(b *strings.Builder, blocks []Block, opts LaTeXOptions) {
+	for _, bl := range blocks {
+		switch bl.Kind {
+		case BlockParagraph:
+			if len(bl.Inlines) == 0 {
+				b.WriteString("\\mbox{}\\par\n")
+				continue
+			}
+			b.WriteString("\\noindent ")
+			latexInlines(b, bl.Inlines, opts)
+			b.WriteString("\\par\n")
+		case BlockHeading:
+			fmt.Fprintf(b, "%s{", latexHeadings[bl.Level])
+			latexInlines(b, bl.Inlines, opts)
+			b.WriteString("}\n")
+		case BlockPageBreak:
+			b.WriteString("\\newpage\n")
+		case BlockIndent:
+			fmt.Fprintf(b, "\\begin{jisage}{%d}\n", bl.Level)
+			latexBlocks(b, bl.Blocks, opts)
+			b.WriteString("\\end{jisage}\n")
+		case BlockBottom:
+			fmt.Fprintf(b, "\\begin{jiage}{%d}\n", bl.Level)
+			latexBlocks(b, bl.Blocks, opts)
+			b.WriteString("\\end{jiage}\n")
+		}
+	}
+}
+
+func latexInlines(b *strings.Builder, inlines []Inline, opts LaTeXOptions) {
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineText:
+			b.WriteString(latexEscaper.Replace(in.Text))
+		case InlineRuby:
+			fmt.Fprintf(b, "\\ruby{%s}{%s}", latexEscaper.Replace(in.Text), latexEscaper.Replace(in.Ruby))
+		case InlineEmphasis:
+			cmd, ok := latexEmphasis[in.Style]
+			if !ok {
+				cmd = `\kenten`
+			}
+			b.WriteString(cmd + "{")
+			latexInlines(b, in.Children, opts)
+			b.WriteString("}")
+		case InlineTateChuYoko:
+			b.WriteString("\\tatechuyoko{")
+			latexInlines(b, in.Children, opts)
+			b.WriteString("}")
+		case InlineGaiji:
+			switch {
+			case in.Text == "":
+				// comments end at the line break, which is not a space in Japanese text
+				fmt.Fprintf(b, "※%%［＃%s］\n", in.Annotation)
+			case opts.UTF && utf8.RuneCountInString(in.Text) == 1 && !in0208(in.Text):
+				r, _ := utf8.DecodeRuneInString(in.Text)
+				fmt.Fprintf(b, "\\UTF{%04X}", r)
+			default:
+				b.WriteString(latexEscaper.Replace(in.Text))
+			}
+		case InlineNote:
+			fmt.Fprintf(b, "%%［＃%s］\n", in.Annotation)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteLaTeX(t *testing.T) {
+	doc := ParseDocument(documentSample)
+	var buf bytes.Buffer
+	if err := WriteLaTeX(&buf, doc, LaTeXOptions{}); err != nil {
+		t.Fatalf("WriteLaTeX error: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"\\documentclass[tate,book]{jlreq}\n",
+		"\\title{吾輩は猫である}\n\\author{夏目漱石}\n",
+		"\\begin{jisage}{3}\n\\section*{一}\n\\end{jisage}\n",
+		"\\noindent 　\\ruby{吾輩}{わがはい}は\\ruby{猫である}{ねこである}。\\par\n",
+		"\\noindent \\kenten{じめじめ}した所で挘いた。\\par\n",
+		"\\begin{jisage}{2}\n\\noindent 明治\\tatechuyoko{３８}年\\par\n",
+		"\\noindent \\uline{強調}%［＃不明な注記］\n\\par\n\\end{jisage}\n",
+		"\\newpage\n\\begin{jiage}{0}\n\\noindent 夏目\\par\n\\end{jiage}\n",
+		"\\end{document}\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteLaTeX got:\n%s\nwant containing: %q", got, want)
+		}
+	}
+	if strings.Contains(got, "{otf}") {
+		t.Errorf("WriteLaTeX uses otf package without UTF option")
+	}
+}
+
+func TestWriteLaTeXGaiji(t *testing.T) {
+	testcases := []struct {
+		in   string
+		opts LaTeXOptions
+		want string
+	}{
+		{"※［＃「てへん＋劣」、第3水準1-84-77］", LaTeXOptions{}, "挘"},
+		{"※［＃「てへん＋劣」、第3水準1-84-77］", LaTeXOptions{UTF: true}, "\\UTF{6318}"},
+		{"※［＃「口＋世」、U+546D］", LaTeXOptions{UTF: true}, "\\UTF{546D}"},
+		{"※［＃「さんずい＋不明」］", LaTeXOptions{}, "※%［＃「さんずい＋不明」］\n"},
+		{"100%の{言}", LaTeXOptions{}, "100\\%の\\{言\\}"},
+	}
+	for _, tc := range testcases {
+		var b strings.Builder
+		inlines, _ := parseInlines(tc.in)
+		latexInlines(&b, inlines, tc.opts)
+		if got := b.String(); got != tc.want {
+			t.Errorf("latexInlines(%q) got: %q want: %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LineEnding is a line ending conversion mode
+type LineEnding int
+
+// Line ending conversion modes
+const (
+	// EOLPreserve keeps line endings as is
+	EOLPreserve LineEnding = iota
+	// EOLCRLF converts line endings into CRLF (Aozora Bunko submission rule)
+	EOLCRLF
+	// EOLLF converts line endings into LF
+	EOLLF
+)
+
+// BOMMode is a byte order mark conversion mode
+type BOMMode int
+
+// BOM conversion modes
+const (
+	// BOMPreserve keeps BOM as is
+	BOMPreserve BOMMode = iota
+	// BOMStrip removes BOM at the beginning of text
+	BOMStrip
+	// BOMAdd adds BOM at the beginning of text (UTF-8 output only)
+	BOMAdd
+)
+
+const bom = "\uFEFF"
+
+// ConvLineEnding converts all line endings (CRLF, LF and lone CR) into eol
+func ConvLineEnding(str string, eol LineEnding) string {
+	var sep string
+	switch eol {
+	case EOLCRLF:
+		sep = "\r\n"
+	case EOLLF:
+		sep = "\n"
+	default:
+		return str
+	}
+	str = strings.Replace(str, "\r\n", "\n", -1)
+	str = strings.Replace(str, "\r", "\n", -1)
+	if sep != "\n" {
+		str = strings.Replace(str, "\n", sep, -1)
+	}
+	return str
+}
+
+// ConvBOM strips or adds BOM at the beginning of str
+func ConvBOM(str string, mode BOMMode) string {
+	switch mode {
+	case BOMStrip:
+		return strings.TrimPrefix(str, bom)
+	case BOMAdd:
+		if !strings.HasPrefix(str, bom) {
+			return bom + str
+		}
+	}
+	return str
+}
+
+// IssueKind is a kind of problem found by CheckText
+type IssueKind int
+
+// Kinds of issue
+const (
+	// IssueBOM is BOM at the beginning of text
+	IssueBOM IssueKind = iota + 1
+	// IssueMixedLineEnding is a line ending different from the first line
+	IssueMixedLineEnding
+	// IssueLoneCR is a CR not followed by LF
+	IssueLoneCR
+	// IssueTrailingSpace is whitespace at the end of line
+	IssueTrailingSpace
+)
+
+// String returns the description of the kind
+func (k IssueKind) String() string {
+	switch k {
+	case IssueBOM:
+		return "BOM"
+	case IssueMixedLineEnding:
+		return "mixed line ending"
+	case IssueLoneCR:
+		return "lone CR"
+	case IssueTrailingSpace:
+		return "trailing whitespace"
+	}
+	return "unknown"
+}
+
+// Issue is a problem found by CheckText
+type Issue struct {
+	Line int // 1-origin line number
+	Kind IssueKind
+}
+
+// String returns a report line of the issue
+func (i Issue) String() string {
+	return fmt.Sprintf("%d: %v", i.Line, i.Kind)
+}
+
+// CheckText checks str against Aozora Bunko submission rules:
+// BOM, mixed line endings, lone CR and trailing whitespace.
+func CheckText(str string) []Issue {
+	var issues []Issue
+	if strings.HasPrefix(str, bom) {
+		issues = append(issues, Issue{Line: 1, Kind: IssueBOM})
+	}
+	first := ""
+	for line := 1; str != ""; line++ {
+		i := strings.IndexAny(str, "\r\n")
+		text, eol := str, ""
+		if i >= 0 {
+			text, eol = str[:i], str[i:i+1]
+			if strings.HasPrefix(str[i:], "\r\n") {
+				eol = "\r\n"
+			}
+		}
+		str = str[len(text)+len(eol):]
+
+		if strings.TrimRight(text, " \t　") != text {
+			issues = append(issues, Issue{Line: line, Kind: IssueTrailingSpace})
+		}
+		if eol == "\r" {
+			issues = append(issues, Issue{Line: line, Kind: IssueLoneCR})
+		}
+		if first == "" {
+			first = eol
+		} else if eol != "" && eol != first {
+			issues = append(issues, Issue{Line: line, Kind: IssueMixedLineEnding})
+		}
+	}
+	return issues
+}
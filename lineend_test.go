@@ -0,0 +1,92 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvLineEnding(t *testing.T) {
+	var convertedPairs = []struct {
+		in  string
+		eol LineEnding
+		out string
+	}{
+		{"a\nb\n", EOLCRLF, "a\r\nb\r\n"},
+		{"a\r\nb\n", EOLCRLF, "a\r\nb\r\n"},
+		{"a\rb\r\n", EOLCRLF, "a\r\nb\r\n"},
+		{"a\r\nb\r\n", EOLLF, "a\nb\n"},
+		{"a\r\nb\n", EOLPreserve, "a\r\nb\n"},
+	}
+	for _, tt := range convertedPairs {
+		if got := ConvLineEnding(tt.in, tt.eol); got != tt.out {
+			t.Errorf("ConvLineEnding(%q) got: %q want: %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestConvBOM(t *testing.T) {
+	var convertedPairs = []struct {
+		in   string
+		mode BOMMode
+		out  string
+	}{
+		{"\uFEFFあ", BOMStrip, "あ"},
+		{"あ", BOMStrip, "あ"},
+		{"あ", BOMAdd, "\uFEFFあ"},
+		{"\uFEFFあ", BOMAdd, "\uFEFFあ"},
+		{"\uFEFFあ", BOMPreserve, "\uFEFFあ"},
+	}
+	for _, tt := range convertedPairs {
+		if got := ConvBOM(tt.in, tt.mode); got != tt.out {
+			t.Errorf("ConvBOM(%q) got: %q want: %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	var convertedPairs = []struct {
+		in     string
+		issues []Issue
+	}{
+		{"あ\r\nい\r\n", nil},
+		{"\uFEFFあ\r\n", []Issue{{1, IssueBOM}}},
+		{"あ\r\nい\nう\r\n", []Issue{{2, IssueMixedLineEnding}}},
+		{"あ\r\nい\rう\r\n", []Issue{{2, IssueLoneCR}, {2, IssueMixedLineEnding}}},
+		{"あ \r\nい　\r\nう\t", []Issue{{1, IssueTrailingSpace}, {2, IssueTrailingSpace}, {3, IssueTrailingSpace}}},
+	}
+	for _, tt := range convertedPairs {
+		if got := CheckText(tt.in); !reflect.DeepEqual(got, tt.issues) {
+			t.Errorf("CheckText(%q) got: %v want: %v", tt.in, got, tt.issues)
+		}
+	}
+}
+
+func TestEncodeWithLineEnding(t *testing.T) {
+	output := new(bytes.Buffer)
+	if err := Encode(strings.NewReader("\uFEFFあ\nい\n"), output); err == nil {
+		t.Errorf("Encode should be error with BOM")
+	}
+
+	output.Reset()
+	err := Encode(strings.NewReader("\uFEFFあ\nい\n"), output, WithBOM(BOMStrip), WithLineEnding(EOLCRLF))
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("あ\r\nい\r\n"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+
+	if err := Encode(strings.NewReader("あ"), output, WithBOM(BOMAdd)); err == nil {
+		t.Errorf("Encode should be error with BOMAdd")
+	}
+
+	decoded := new(bytes.Buffer)
+	if err := Decode(output, decoded, WithBOM(BOMAdd), WithLineEnding(EOLLF)); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if got, want := decoded.String(), "\uFEFFあ\nい\n"; got != want {
+		t.Errorf("Decode got: %q want: %q", got, want)
+	}
+}
@@ -1,7 +1,13 @@
 package aozoraconv
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
+
+	"github.com/takahashim/aozoraconv/internal/tablegen"
 )
 
 func TestParseLine(test *testing.T) {
@@ -22,3 +28,65 @@ func TestParseLine(test *testing.T) {
 			m, k, t, uni, uni2, err, s)
 	}
 }
+
+// TestGenerateTables checks the generator reproduces tables.go from the mapping
+// table written back from the tables
+func TestGenerateTables(t *testing.T) {
+	added := map[JISCode]bool{}
+	for _, c := range jis0213Added2004 {
+		added[c] = true
+	}
+	fullwidth := map[JISCode]rune{}
+	for _, p := range jis0213Fullwidth {
+		fullwidth[p.code] = p.r1
+	}
+	var b strings.Builder
+	b.WriteString("## Date: test\n")
+	for men := 1; men <= 2; men++ {
+		for ku := 1; ku <= 94; ku++ {
+			for ten := 1; ten <= 94; ten++ {
+				chr := []rune(decodeJis(men, ku, ten))
+				if len(chr) == 0 {
+					continue
+				}
+				fmt.Fprintf(&b, "%d-%02X%02X\tU+%04X", men+2, ku+32, ten+32, chr[0])
+				if len(chr) > 1 {
+					fmt.Fprintf(&b, "+%04X", chr[1])
+				}
+				b.WriteString("\t#")
+				c := JISCode{int8(men), int8(ku), int8(ten)}
+				if added[c] {
+					b.WriteString(" [2004]")
+				}
+				if r, ok := fullwidth[c]; ok {
+					fmt.Fprintf(&b, " Fullwidth: U+%04X", r)
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+	got, err := tablegen.Generate([]byte(b.String()), "jisx0213-2004-std.txt")
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	want, err := ioutil.ReadFile("tables.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the header records the source, which is not the mapping table above
+	body := func(s []byte) string {
+		return string(s[bytes.Index(s, []byte("// Package aozoraconv")):])
+	}
+	if body(got) != body(want) {
+		gl, wl := strings.Split(body(got), "\n"), strings.Split(body(want), "\n")
+		for i := range gl {
+			if i >= len(wl) || gl[i] != wl[i] {
+				t.Fatalf("Generate differs from tables.go at line %d of the body:\n got: %s", i+1, gl[i])
+			}
+		}
+		t.Fatalf("Generate differs from tables.go: got %d lines, want %d lines", len(gl), len(wl))
+	}
+	if !bytes.HasPrefix(got, []byte("// Code generated by maketables.go; DO NOT EDIT.\n// Source: jisx0213-2004-std.txt (Date: test)\n// SHA-256: ")) {
+		t.Errorf("Generate header got: %s", got[:200])
+	}
+}
@@ -0,0 +1,68 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// JISVersion is a version of JIS X 0213 used for the mapping
+type JISVersion int
+
+// Versions of JIS X 0213
+const (
+	// JIS2004 is JIS X 0213:2004 (default)
+	JIS2004 JISVersion = iota
+	// JIS2000 is JIS X 0213:2000, without 10 characters added in 2004
+	JIS2000
+)
+
+// String returns the name of the version
+func (v JISVersion) String() string {
+	switch v {
+	case JIS2004:
+		return "JIS X 0213:2004"
+	case JIS2000:
+		return "JIS X 0213:2000"
+	}
+	return "unknown"
+}
+
+// compatAlternatives are alternative mappings of Aozora Bunko (and CP932) convention,
+// sorted by runes. They take precedence over jis0213Fullwidth.
+var compatAlternatives = [...]jisPair{
+	{0x2015, 0, JISCode{1, 1, 29}}, // "―" -> "—"
+	{0xFF0D, 0, JISCode{1, 1, 61}}, // "－" -> "−"
+	{0xFF5E, 0, JISCode{1, 1, 33}}, // "～" -> "〜"
+}
+
+// Jis2Uni returns a string from jis codepoint in the version
+func (v JISVersion) Jis2Uni(men, ku, ten int) (string, error) {
+	if v == JIS2000 && Is2004(men, ku, ten) {
+		return "", fmt.Errorf("%d-%d-%d is not in %v", men, ku, ten, v)
+	}
+	return Jis2Uni(men, ku, ten)
+}
+
+// Uni2Jis returns JISCode of a character (1 or 2 runes) in the version.
+// Alternative mappings like U+FF5E for 1-1-33 are also accepted.
+func (v JISVersion) Uni2Jis(str string) (JISCode, error) {
+	jis, err := uni2jis(str)
+	if err != nil {
+		r, n := utf8.DecodeRuneInString(str)
+		if n == 0 || n != len(str) {
+			return jis, err
+		}
+		alt, ok := lookupPair(compatAlternatives[:], r, 0)
+		if !ok {
+			alt, ok = lookupPair(jis0213Fullwidth[:], r, 0)
+		}
+		if !ok {
+			return jis, err
+		}
+		jis = alt
+	}
+	if v == JIS2000 && Is2004(jis.Men(), jis.Ku(), jis.Ten()) {
+		return JISCode{}, fmt.Errorf("%q is not in %v", str, v)
+	}
+	return jis, nil
+}
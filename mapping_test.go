@@ -0,0 +1,59 @@
+package aozoraconv
+
+import (
+	"testing"
+)
+
+func TestJISVersionUni2Jis(t *testing.T) {
+	var convertedPairs = []struct {
+		version   JISVersion
+		in        string
+		out       JISCode
+		isSuccess bool
+	}{
+		{JIS2004, "〜", JISCode{1, 1, 33}, true},
+		{JIS2004, "～", JISCode{1, 1, 33}, true},
+		{JIS2004, "？", JISCode{1, 1, 9}, true},
+		{JIS2004, "－", JISCode{1, 1, 61}, true},
+		{JIS2004, "―", JISCode{1, 1, 29}, true},
+		{JIS2004, "￥", JISCode{1, 1, 79}, true},
+		{JIS2004, "俱", JISCode{1, 14, 1}, true},
+		{JIS2004, "?", JISCode{}, false},
+		{JIS2000, "亜", JISCode{1, 16, 1}, true},
+		{JIS2000, "～", JISCode{1, 1, 33}, true},
+		{JIS2000, "俱", JISCode{}, false},
+		{JIS2000, "𠮟", JISCode{}, false},
+	}
+	for _, tt := range convertedPairs {
+		got, err := tt.version.Uni2Jis(tt.in)
+		if got != tt.out {
+			t.Errorf("%v: Uni2Jis(%q) got: %v want: %v", tt.version, tt.in, got, tt.out)
+		}
+		if (err == nil) != tt.isSuccess {
+			t.Errorf("%v: Uni2Jis(%q) error: %v", tt.version, tt.in, err)
+		}
+	}
+}
+
+func TestJISVersionJis2Uni(t *testing.T) {
+	var convertedPairs = []struct {
+		version      JISVersion
+		men, ku, ten int
+		out          string
+		isSuccess    bool
+	}{
+		{JIS2004, 1, 14, 1, "俱", true},
+		{JIS2000, 1, 14, 1, "", false},
+		{JIS2000, 1, 94, 94, "", false},
+		{JIS2000, 1, 16, 1, "亜", true},
+	}
+	for _, tt := range convertedPairs {
+		got, err := tt.version.Jis2Uni(tt.men, tt.ku, tt.ten)
+		if got != tt.out {
+			t.Errorf("%v: Jis2Uni(%d, %d, %d) got: %q want: %q", tt.version, tt.men, tt.ku, tt.ten, got, tt.out)
+		}
+		if (err == nil) != tt.isSuccess {
+			t.Errorf("%v: Jis2Uni(%d, %d, %d) error: %v", tt.version, tt.men, tt.ku, tt.ten, err)
+		}
+	}
+}
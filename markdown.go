@@ -0,0 +1,211 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MarkdownRuby is a notation of ruby in Markdown
+type MarkdownRuby int
+
+// Notations of ruby in Markdown
+const (
+	// MarkdownRubyHTML is `<ruby>漢字<rt>かんじ</rt></ruby>`
+	MarkdownRubyHTML MarkdownRuby = iota
+	// MarkdownRubyBrace is `{漢字|かんじ}`
+	MarkdownRubyBrace
+)
+
+// MarkdownEmphasis is a notation of 傍点 and 傍線 in Markdown
+type MarkdownEmphasis int
+
+// Notations of emphasis in Markdown
+const (
+	// MarkdownEmphasisStrong is `**…**`
+	MarkdownEmphasisStrong MarkdownEmphasis = iota
+	// MarkdownEmphasisHTML is `<em class="sesame">…</em>`
+	MarkdownEmphasisHTML
+)
+
+// MarkdownOptions are options of WriteMarkdown
+type MarkdownOptions struct {
+	Ruby     MarkdownRuby
+	Emphasis MarkdownEmphasis
+}
+
+// markdownEscaper escapes ASCII punctuations which can be Markdown syntax
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`|`, `\|`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`#`, `\#`,
+)
+
+// markdownEmClass returns the class of `<em>` for the emphasis style
+func markdownEmClass(style string) string {
+	if style == "傍点" {
+		return "sesame"
+	}
+	for c, name := range xhtmlBouten {
+		if name == style {
+			return c
+		}
+	}
+	return ""
+}
+
+// WriteMarkdown writes doc as Markdown. The title and the author are front matter,
+// headings are `#`, `##` and `###`, 改ページ is `---`, 字下げ and 地付き blocks are
+// `<div>` with classes of Aozora Bunko XHTML, 縦中横 is `<span class="tcy">`, and annotations
+// not interpreted are HTML comments. Emphasis not flanking in CommonMark is `<strong>` or `<em>`.
+func WriteMarkdown(w io.Writer, doc *Document, opts MarkdownOptions) error {
+	var b strings.Builder
+	if doc.Title != "" {
+		fmt.Fprintf(&b, "---\ntitle: %s\nauthor: %s\n---\n\n", strconv.Quote(doc.Title), strconv.Quote(doc.Author))
+	}
+	markdownBlocks(&b, doc.Blocks, opts)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownBlocks(b *strings.Builder, blocks []Block, opts MarkdownOptions) {
+	for _, bl := range blocks {
+		switch bl.Kind {
+		case BlockParagraph:
+			if len(bl.Inlines) == 0 {
+				b.WriteString("<br>\n\n")
+				continue
+			}
+			markdownInlines(b, bl.Inlines, opts)
+			b.WriteString("\n\n")
+		case BlockHeading:
+			b.WriteString(strings.Repeat("#", bl.Level) + " ")
+			markdownInlines(b, bl.Inlines, opts)
+			b.WriteString("\n\n")
+		case BlockPageBreak:
+			b.WriteString("---\n\n")
+		case BlockIndent:
+			fmt.Fprintf(b, "<div class=\"jisage_%d\">\n\n", bl.Level)
+			markdownBlocks(b, bl.Blocks, opts)
+			b.WriteString("</div>\n\n")
+		case BlockBottom:
+			fmt.Fprintf(b, "<div class=\"chitsuki_%d\">\n\n", bl.Level)
+			markdownBlocks(b, bl.Blocks, opts)
+			b.WriteString("</div>\n\n")
+		}
+	}
+}
+
+func markdownInlines(b *strings.Builder, inlines []Inline, opts MarkdownOptions) {
+	parts := make([]string, len(inlines))
+	for i, in := range inlines {
+		parts[i] = markdownInline(in, opts)
+	}
+	for i, in := range inlines {
+		if in.Kind == InlineEmphasis {
+			// the following text decides whether `**` can close the emphasis
+			markdownEmphasis(b, in, opts, strings.Join(parts[i+1:], ""))
+			continue
+		}
+		b.WriteString(parts[i])
+	}
+}
+
+// markdownInline returns in as Markdown; emphasis is written by markdownEmphasis,
+// and is only the placeholder of its delimiter here
+func markdownInline(in Inline, opts MarkdownOptions) string {
+	var b strings.Builder
+	switch in.Kind {
+	case InlineText:
+		b.WriteString(markdownEscaper.Replace(in.Text))
+	case InlineRuby:
+		if opts.Ruby == MarkdownRubyBrace {
+			fmt.Fprintf(&b, "{%s|%s}", markdownEscaper.Replace(in.Text), markdownEscaper.Replace(in.Ruby))
+		} else {
+			fmt.Fprintf(&b, "<ruby>%s<rt>%s</rt></ruby>", markdownEscaper.Replace(in.Text), markdownEscaper.Replace(in.Ruby))
+		}
+	case InlineEmphasis:
+		b.WriteString("*")
+	case InlineTateChuYoko:
+		b.WriteString("<span class=\"tcy\">")
+		markdownInlines(&b, in.Children, opts)
+		b.WriteString("</span>")
+	case InlineGaiji:
+		if in.Text != "" {
+			b.WriteString(markdownEscaper.Replace(in.Text))
+		} else {
+			fmt.Fprintf(&b, "※<!-- ※［＃%s］ -->", markdownComment(in.Annotation))
+		}
+	case InlineNote:
+		fmt.Fprintf(&b, "<!-- ［＃%s］ -->", markdownComment(in.Annotation))
+	}
+	return b.String()
+}
+
+// markdownEmphasis writes emphasis in; next is the text after it. `**` and `*`
+// fall back to HTML if they are not flanking in CommonMark like `**「強調」**した`.
+func markdownEmphasis(b *strings.Builder, in Inline, opts MarkdownOptions, next string) {
+	var inner strings.Builder
+	markdownInlines(&inner, in.Children, opts)
+	start, end := "**", "**"
+	switch {
+	case in.Style == "斜体":
+		start, end = "*", "*"
+	case opts.Emphasis == MarkdownEmphasisHTML && in.Style == "太字":
+		start, end = "<strong>", "</strong>"
+	case opts.Emphasis == MarkdownEmphasisHTML:
+		start, end = "<em>", "</em>"
+		if c := markdownEmClass(in.Style); c != "" {
+			start = fmt.Sprintf("<em class=\"%s\">", c)
+		}
+	}
+	if strings.HasPrefix(start, "*") && !markdownFlanking(b.String(), inner.String(), next) {
+		start, end = "<strong>", "</strong>"
+		if in.Style == "斜体" {
+			start, end = "<em>", "</em>"
+		}
+	}
+	b.WriteString(start)
+	b.WriteString(inner.String())
+	b.WriteString(end)
+}
+
+// markdownFlanking checks delimiters around inner can open and close emphasis:
+// the opening one is left-flanking and the closing one is right-flanking
+func markdownFlanking(before, inner, after string) bool {
+	if inner == "" {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(before)
+	first, _ := utf8.DecodeRuneInString(inner)
+	last, _ := utf8.DecodeLastRuneInString(inner)
+	next, _ := utf8.DecodeRuneInString(after)
+	boundary := func(r rune, s string) bool {
+		return s == "" || unicode.IsSpace(r) || markdownPunct(r)
+	}
+	open := !unicode.IsSpace(first) && (!markdownPunct(first) || boundary(prev, before))
+	close := !unicode.IsSpace(last) && (!markdownPunct(last) || boundary(next, after))
+	return open && close
+}
+
+// markdownPunct checks r is a punctuation character of CommonMark
+func markdownPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// markdownComment makes s safe in HTML comments
+func markdownComment(s string) string {
+	return strings.Replace(s, "--", "－－", -1)
+}
@@ -0,0 +1,57 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, ParseDocument(documentSample), MarkdownOptions{}); err != nil {
+		t.Fatalf("WriteMarkdown error: %v", err)
+	}
+	want := "---\ntitle: \"吾輩は猫である\"\nauthor: \"夏目漱石\"\n---\n\n" +
+		"<div class=\"jisage_3\">\n\n# 一\n\n</div>\n\n" +
+		"　<ruby>吾輩<rt>わがはい</rt></ruby>は<ruby>猫である<rt>ねこである</rt></ruby>。\n\n" +
+		"**じめじめ**した所で挘いた。\n\n" +
+		"<div class=\"jisage_2\">\n\n" +
+		"明治<span class=\"tcy\">３８</span>年\n\n" +
+		"**強調**<!-- ［＃不明な注記］ -->\n\n" +
+		"</div>\n\n" +
+		"---\n\n" +
+		"<div class=\"chitsuki_0\">\n\n夏目\n\n</div>\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteMarkdown got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarkdownInlines(t *testing.T) {
+	testcases := []struct {
+		in   string
+		opts MarkdownOptions
+		want string
+	}{
+		{"｜漢字《かんじ》", MarkdownOptions{}, "<ruby>漢字<rt>かんじ</rt></ruby>"},
+		{"｜漢字《かんじ》", MarkdownOptions{Ruby: MarkdownRubyBrace}, "{漢字|かんじ}"},
+		{"強調［＃「強調」に傍点］", MarkdownOptions{Emphasis: MarkdownEmphasisHTML}, "<em class=\"sesame\">強調</em>"},
+		{"強調［＃「強調」に丸傍点］", MarkdownOptions{Emphasis: MarkdownEmphasisHTML}, "<em class=\"black_circle\">強調</em>"},
+		{"強調［＃「強調」に太字］", MarkdownOptions{Emphasis: MarkdownEmphasisHTML}, "<strong>強調</strong>"},
+		{"強調［＃「強調」に斜体］", MarkdownOptions{}, "*強調*"},
+		{"※［＃「さんずい＋不明」］", MarkdownOptions{}, "※<!-- ※［＃「さんずい＋不明」］ -->"},
+		{"a*b_c#d", MarkdownOptions{}, "a\\*b\\_c\\#d"},
+		{"「強調」［＃「「強調」」に太字］した", MarkdownOptions{}, "<strong>「強調」</strong>した"},
+		{"「強調」［＃「「強調」」に太字］", MarkdownOptions{}, "**「強調」**"},
+		{"彼は「強調」［＃「「強調」」に斜体］と", MarkdownOptions{}, "彼は<em>「強調」</em>と"},
+		{"あ強調［＃「強調」に傍点］い", MarkdownOptions{}, "あ**強調**い"},
+		{"１２［＃「１２」は縦中横］", MarkdownOptions{}, "<span class=\"tcy\">１２</span>"},
+	}
+	for _, tc := range testcases {
+		var b strings.Builder
+		inlines, _ := parseInlines(tc.in)
+		markdownInlines(&b, inlines, tc.opts)
+		if got := b.String(); got != tc.want {
+			t.Errorf("markdownInlines(%q) got: %q want: %q", tc.in, got, tc.want)
+		}
+	}
+}
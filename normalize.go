@@ -0,0 +1,231 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormRule is a set of normalization rules for Normalize
+type NormRule int
+
+// Normalization rules
+const (
+	// NormKana widens half-width katakana and punctuation (`ﾃﾞ` -> `デ`)
+	NormKana NormRule = 1 << iota
+	// NormMark widens ASCII `!` and `?` following Japanese text
+	NormMark
+	// NormDigit widens ASCII digits surrounded by Japanese text
+	NormDigit
+	// NormEnclosed expands circled and parenthesized numbers into gaiji annotations
+	NormEnclosed
+
+	// NormAll is the set of all rules
+	NormAll = NormKana | NormMark | NormDigit | NormEnclosed
+)
+
+// String returns the name of the rule
+func (rule NormRule) String() string {
+	var names []string
+	for _, n := range []struct {
+		rule NormRule
+		name string
+	}{
+		{NormKana, "kana"},
+		{NormMark, "mark"},
+		{NormDigit, "digit"},
+		{NormEnclosed, "enclosed"},
+	} {
+		if rule&n.rule != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// ParseNormRule parses comma separated rule names such as "kana,digit" or "all"
+func ParseNormRule(s string) (NormRule, error) {
+	var rule NormRule
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(name) {
+		case "all":
+			rule |= NormAll
+		case "kana":
+			rule |= NormKana
+		case "mark":
+			rule |= NormMark
+		case "digit":
+			rule |= NormDigit
+		case "enclosed":
+			rule |= NormEnclosed
+		default:
+			return 0, fmt.Errorf("unknown normalization rule: %q", name)
+		}
+	}
+	return rule, nil
+}
+
+// Replacement is a replacement done by Normalize
+type Replacement struct {
+	Rule NormRule
+	From string
+	To   string
+}
+
+// NormalizeSummary counts replacements done by Normalize
+type NormalizeSummary map[Replacement]int
+
+// Total returns the number of replacements by rule
+func (s NormalizeSummary) Total(rule NormRule) int {
+	n := 0
+	for r, c := range s {
+		if r.Rule&rule != 0 {
+			n += c
+		}
+	}
+	return n
+}
+
+// Replacements returns the replacements in the order of rule, From and To
+func (s NormalizeSummary) Replacements() []Replacement {
+	ret := make([]Replacement, 0, len(s))
+	for r := range s {
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Rule != ret[j].Rule {
+			return ret[i].Rule < ret[j].Rule
+		}
+		if ret[i].From != ret[j].From {
+			return ret[i].From < ret[j].From
+		}
+		return ret[i].To < ret[j].To
+	})
+	return ret
+}
+
+// enclosedNumbers are ranges of enclosed numbers and their annotation names
+var enclosedNumbers = []struct {
+	low, high rune
+	first     int
+	name      string
+}{
+	{0x2460, 0x2473, 1, "丸"},      // ① .. ⑳
+	{0x3251, 0x325F, 21, "丸"},     // ㉑ .. ㉟
+	{0x32B1, 0x32BF, 36, "丸"},     // ㊱ .. ㊿
+	{0x2776, 0x277F, 1, "黒丸"},     // ❶ .. ❿
+	{0x24EB, 0x24F4, 11, "黒丸"},    // ⓫ .. ⓴
+	{0x2474, 0x2487, 1, "括弧付き"},   // ⑴ .. ⒇
+	{0x2488, 0x249B, 1, "ピリオド付き"}, // ⒈ .. ⒛
+}
+
+var annotationRe = regexp.MustCompile(`［＃[^］]*］`)
+
+// Normalize converts characters prohibited in Aozora Bunko format
+// into preferred forms, according to rules.
+// Annotations (`［＃…］`) are left as is.
+// Replacements are counted in summary unless it is nil.
+func Normalize(str string, rules NormRule, summary NormalizeSummary) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range annotationRe.FindAllStringIndex(str, -1) {
+		b.WriteString(normalizeText(str[last:loc[0]], rules, summary))
+		b.WriteString(str[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(normalizeText(str[last:], rules, summary))
+	return b.String()
+}
+
+func normalizeText(str string, rules NormRule, summary NormalizeSummary) string {
+	var b strings.Builder
+	r := []rune(str)
+	for i := 0; i < len(r); {
+		from, to, rule := "", "", NormRule(0)
+		n := 1
+		switch {
+		case rules&NormKana != 0 && isHalfwidthKana(r[i]):
+			if i+1 < len(r) && (r[i+1] == 'ﾞ' || r[i+1] == 'ﾟ') {
+				n = 2
+			}
+			from, rule = string(r[i:i+n]), NormKana
+			to = widenKana(from)
+		case rules&NormMark != 0 && (r[i] == '!' || r[i] == '?') && !isASCIIContext(r, i-1):
+			for i+n < len(r) && (r[i+n] == '!' || r[i+n] == '?') {
+				n++
+			}
+			from, rule = string(r[i:i+n]), NormMark
+			to = widenASCII(from)
+		case rules&NormDigit != 0 && '0' <= r[i] && r[i] <= '9' && !isASCIIContext(r, i-1):
+			for i+n < len(r) && '0' <= r[i+n] && r[i+n] <= '9' {
+				n++
+			}
+			if isASCIIContext(r, i+n) {
+				b.WriteString(string(r[i : i+n]))
+				i += n
+				continue
+			}
+			from, rule = string(r[i:i+n]), NormDigit
+			to = widenASCII(from)
+		case rules&NormEnclosed != 0 && enclosedAnnotation(r[i]) != "":
+			from, rule = string(r[i]), NormEnclosed
+			to = enclosedAnnotation(r[i])
+		default:
+			b.WriteRune(r[i])
+			i++
+			continue
+		}
+		b.WriteString(to)
+		if summary != nil {
+			summary[Replacement{Rule: rule, From: from, To: to}]++
+		}
+		i += n
+	}
+	return b.String()
+}
+
+// isHalfwidthKana checks r is half-width katakana or punctuation
+func isHalfwidthKana(r rune) bool {
+	return 0xFF61 <= r && r <= 0xFF9F
+}
+
+// isASCIIContext checks r[i] is an ASCII character except for line breaks
+func isASCIIContext(r []rune, i int) bool {
+	if i < 0 || i >= len(r) {
+		return false
+	}
+	return r[i] < utf8.RuneSelf && r[i] != '\n' && r[i] != '\r'
+}
+
+// widenKana converts half-width katakana into full-width one,
+// joining voiced sound marks
+func widenKana(s string) string {
+	ret := norm.NFKC.String(s)
+	// voiced sound marks which can not be composed (`ｱﾞ`)
+	return strings.NewReplacer("\u3099", "゛", "\u309A", "゜").Replace(ret)
+}
+
+// widenASCII converts ASCII characters into full-width forms
+func widenASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		return r - 0x21 + 0xFF01
+	}, s)
+}
+
+// enclosedAnnotation returns gaiji annotation for enclosed numbers like `①`
+func enclosedAnnotation(r rune) string {
+	for _, e := range enclosedNumbers {
+		if e.low <= r && r <= e.high {
+			desc := fmt.Sprintf("%s%d", e.name, int(r-e.low)+e.first)
+			if jis, err := Uni2Jis(string(r)); err == nil {
+				return fmt.Sprintf("※［＃%s、%s］", desc, jis.Ref())
+			}
+			return fmt.Sprintf("※［＃%s、U+%04X］", desc, r)
+		}
+	}
+	return ""
+}
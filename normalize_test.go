@@ -0,0 +1,106 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	var convertedPairs = []struct {
+		in    string
+		rules NormRule
+		out   string
+	}{
+		{"ｱｲｳ", NormAll, "アイウ"},
+		{"ﾃﾞｰﾀﾍﾞｰｽ", NormAll, "データベース"},
+		{"ﾊﾟﾋﾟﾌﾟ", NormAll, "パピプ"},
+		{"ｳﾞｧｲｵﾘﾝ", NormAll, "ヴァイオリン"},
+		{"ｱﾞ", NormAll, "ア゛"},
+		{"｢ｺﾝﾆﾁﾊ｣､ﾄ｡", NormAll, "「コンニチハ」、ト。"},
+		{"ｱｲｳ", NormDigit, "ｱｲｳ"},
+		{"なに!?", NormAll, "なに！？"},
+		{"Hello!", NormAll, "Hello!"},
+		{"第3章", NormAll, "第３章"},
+		{"第12章", NormAll, "第１２章"},
+		{"Windows10の", NormAll, "Windows10の"},
+		{"3.14は", NormAll, "3.14は"},
+		{"第3章", NormMark, "第3章"},
+		{"①", NormAll, "※［＃丸1、1-13-1］"},
+		{"㊿", NormAll, "※［＃丸50、1-8-62］"},
+		{"⑴と⑵", NormAll, "※［＃括弧付き1、U+2474］と※［＃括弧付き2、U+2475］"},
+		{"①", NormKana, "①"},
+		{"※［＃「口＋七」、第3水準1-47-52、123-4］です!", NormAll, "※［＃「口＋七」、第3水準1-47-52、123-4］です！"},
+	}
+	for _, tt := range convertedPairs {
+		if got := Normalize(tt.in, tt.rules, nil); got != tt.out {
+			t.Errorf("Normalize(%v, %v) got: %v want: %v", tt.in, tt.rules, got, tt.out)
+		}
+	}
+}
+
+func TestNormalizeSummary(t *testing.T) {
+	summary := NormalizeSummary{}
+	Normalize("ﾃﾞｰﾀ①ﾃﾞ!", NormAll, summary)
+	if got, want := summary[Replacement{NormKana, "ﾃﾞ", "デ"}], 2; got != want {
+		t.Errorf("NormalizeSummary got: %v want: %v", got, want)
+	}
+	if got, want := summary.Total(NormKana), 4; got != want {
+		t.Errorf("NormalizeSummary.Total(NormKana) got: %v want: %v", got, want)
+	}
+	if got, want := summary.Total(NormAll), 6; got != want {
+		t.Errorf("NormalizeSummary.Total(NormAll) got: %v want: %v", got, want)
+	}
+	got := summary.Replacements()
+	if len(got) != len(summary) {
+		t.Fatalf("NormalizeSummary.Replacements got: %v", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if p, r := got[i-1], got[i]; p.Rule > r.Rule || p.Rule == r.Rule && p.From >= r.From {
+			t.Errorf("NormalizeSummary.Replacements is not sorted: %v", got)
+		}
+	}
+	if got[0] != (Replacement{NormKana, "ｰ", "ー"}) {
+		t.Errorf("NormalizeSummary.Replacements got: %v", got)
+	}
+}
+
+func TestParseNormRule(t *testing.T) {
+	var convertedPairs = []struct {
+		in        string
+		out       NormRule
+		isSuccess bool
+	}{
+		{"all", NormAll, true},
+		{"kana", NormKana, true},
+		{"kana,digit", NormKana | NormDigit, true},
+		{"mark, enclosed", NormMark | NormEnclosed, true},
+		{"foo", 0, false},
+	}
+	for _, tt := range convertedPairs {
+		got, err := ParseNormRule(tt.in)
+		if (err == nil) != tt.isSuccess {
+			t.Errorf("ParseNormRule(%v) error: %v", tt.in, err)
+		}
+		if got != tt.out {
+			t.Errorf("ParseNormRule(%v) got: %v want: %v", tt.in, got, tt.out)
+		}
+	}
+	if got, want := (NormKana | NormDigit).String(), "kana|digit"; got != want {
+		t.Errorf("NormRule.String got: %v want: %v", got, want)
+	}
+}
+
+func TestEncodeWithNormalize(t *testing.T) {
+	output := new(bytes.Buffer)
+	summary := NormalizeSummary{}
+	if err := Encode(strings.NewReader("ﾃﾞｰﾀ①"), output, WithNormalize(NormAll, summary)); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("データ※［＃丸1、1-13-1］"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+	if got, want := summary.Total(NormAll), 4; got != want {
+		t.Errorf("NormalizeSummary.Total got: %v want: %v", got, want)
+	}
+}
@@ -0,0 +1,482 @@
+package aozoraconv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// A small RELAX NG validator for tests, by the derivative algorithm of James Clark
+// (https://relaxng.org/jclark/derivative.html). It supports the simple syntax used in
+// testdata: grammar, define, ref, element, attribute, group, choice, interleave,
+// optional, zeroOrMore, oneOrMore, mixed, text, empty, data, value and list.
+// Datatypes are ID, NCName, token and string with the pattern param. Values of ID
+// attributes must be unique in a document.
+
+type rngKind int
+
+const (
+	rngRef rngKind = iota // not resolved yet
+	rngEmpty
+	rngNotAllowed
+	rngText
+	rngChoice
+	rngInterleave
+	rngGroup
+	rngOneOrMore
+	rngElement
+	rngAttribute
+	rngValue
+	rngData
+	rngList
+	rngAfter
+)
+
+type rngPattern struct {
+	kind   rngKind
+	p1, p2 *rngPattern
+	name   xml.Name
+	value  string
+	match  func(string) bool // rngData
+}
+
+var (
+	rngEmptyPattern      = &rngPattern{kind: rngEmpty}
+	rngNotAllowedPattern = &rngPattern{kind: rngNotAllowed}
+	rngTextPattern       = &rngPattern{kind: rngText}
+)
+
+func rngChoiceOf(p1, p2 *rngPattern) *rngPattern {
+	switch {
+	case p1.kind == rngNotAllowed:
+		return p2
+	case p2.kind == rngNotAllowed:
+		return p1
+	case p1.kind == rngEmpty && p2.kind == rngEmpty:
+		return p1
+	}
+	return &rngPattern{kind: rngChoice, p1: p1, p2: p2}
+}
+
+func rngPair(kind rngKind, p1, p2 *rngPattern) *rngPattern {
+	switch {
+	case p1.kind == rngNotAllowed || p2.kind == rngNotAllowed:
+		return rngNotAllowedPattern
+	case p1.kind == rngEmpty:
+		return p2
+	case p2.kind == rngEmpty:
+		return p1
+	}
+	return &rngPattern{kind: kind, p1: p1, p2: p2}
+}
+
+func rngAfterOf(p1, p2 *rngPattern) *rngPattern {
+	if p1.kind == rngNotAllowed || p2.kind == rngNotAllowed {
+		return rngNotAllowedPattern
+	}
+	return &rngPattern{kind: rngAfter, p1: p1, p2: p2}
+}
+
+func rngOneOrMoreOf(p *rngPattern) *rngPattern {
+	if p.kind == rngNotAllowed {
+		return p
+	}
+	return &rngPattern{kind: rngOneOrMore, p1: p}
+}
+
+func (p *rngPattern) nullable() bool {
+	switch p.kind {
+	case rngGroup, rngInterleave:
+		return p.p1.nullable() && p.p2.nullable()
+	case rngChoice:
+		return p.p1.nullable() || p.p2.nullable()
+	case rngOneOrMore:
+		return p.p1.nullable()
+	case rngEmpty, rngText:
+		return true
+	}
+	return false
+}
+
+func (p *rngPattern) textDeriv(s string) *rngPattern {
+	switch p.kind {
+	case rngChoice:
+		return rngChoiceOf(p.p1.textDeriv(s), p.p2.textDeriv(s))
+	case rngInterleave:
+		return rngChoiceOf(rngPair(rngInterleave, p.p1.textDeriv(s), p.p2), rngPair(rngInterleave, p.p1, p.p2.textDeriv(s)))
+	case rngGroup:
+		q := rngPair(rngGroup, p.p1.textDeriv(s), p.p2)
+		if p.p1.nullable() {
+			return rngChoiceOf(q, p.p2.textDeriv(s))
+		}
+		return q
+	case rngAfter:
+		return rngAfterOf(p.p1.textDeriv(s), p.p2)
+	case rngOneOrMore:
+		return rngPair(rngGroup, p.p1.textDeriv(s), rngChoiceOf(p, rngEmptyPattern))
+	case rngText:
+		return p
+	case rngValue:
+		if strings.Join(strings.Fields(s), " ") == p.value {
+			return rngEmptyPattern
+		}
+	case rngData:
+		if p.match(s) {
+			return rngEmptyPattern
+		}
+	case rngList:
+		q := p.p1
+		for _, token := range strings.Fields(s) {
+			q = q.textDeriv(token)
+		}
+		if q.nullable() {
+			return rngEmptyPattern
+		}
+	}
+	return rngNotAllowedPattern
+}
+
+func (p *rngPattern) applyAfter(f func(*rngPattern) *rngPattern) *rngPattern {
+	switch p.kind {
+	case rngAfter:
+		return rngAfterOf(p.p1, f(p.p2))
+	case rngChoice:
+		return rngChoiceOf(p.p1.applyAfter(f), p.p2.applyAfter(f))
+	}
+	return rngNotAllowedPattern
+}
+
+func (p *rngPattern) startTagOpenDeriv(name xml.Name) *rngPattern {
+	switch p.kind {
+	case rngChoice:
+		return rngChoiceOf(p.p1.startTagOpenDeriv(name), p.p2.startTagOpenDeriv(name))
+	case rngElement:
+		if p.name == name {
+			return rngAfterOf(p.p1, rngEmptyPattern)
+		}
+	case rngInterleave:
+		return rngChoiceOf(
+			p.p1.startTagOpenDeriv(name).applyAfter(func(q *rngPattern) *rngPattern { return rngPair(rngInterleave, q, p.p2) }),
+			p.p2.startTagOpenDeriv(name).applyAfter(func(q *rngPattern) *rngPattern { return rngPair(rngInterleave, p.p1, q) }))
+	case rngOneOrMore:
+		return p.p1.startTagOpenDeriv(name).applyAfter(func(q *rngPattern) *rngPattern {
+			return rngPair(rngGroup, q, rngChoiceOf(p, rngEmptyPattern))
+		})
+	case rngGroup:
+		q := p.p1.startTagOpenDeriv(name).applyAfter(func(q *rngPattern) *rngPattern { return rngPair(rngGroup, q, p.p2) })
+		if p.p1.nullable() {
+			return rngChoiceOf(q, p.p2.startTagOpenDeriv(name))
+		}
+		return q
+	case rngAfter:
+		return p.p1.startTagOpenDeriv(name).applyAfter(func(q *rngPattern) *rngPattern { return rngAfterOf(q, p.p2) })
+	}
+	return rngNotAllowedPattern
+}
+
+func (p *rngPattern) attDeriv(a xml.Attr) *rngPattern {
+	switch p.kind {
+	case rngAfter:
+		return rngAfterOf(p.p1.attDeriv(a), p.p2)
+	case rngChoice:
+		return rngChoiceOf(p.p1.attDeriv(a), p.p2.attDeriv(a))
+	case rngGroup, rngInterleave:
+		return rngChoiceOf(rngPair(p.kind, p.p1.attDeriv(a), p.p2), rngPair(p.kind, p.p1, p.p2.attDeriv(a)))
+	case rngOneOrMore:
+		return rngPair(rngGroup, p.p1.attDeriv(a), rngChoiceOf(p, rngEmptyPattern))
+	case rngAttribute:
+		if p.name == a.Name && (p.p1.nullable() && strings.TrimSpace(a.Value) == "" || p.p1.textDeriv(a.Value).nullable()) {
+			return rngEmptyPattern
+		}
+	}
+	return rngNotAllowedPattern
+}
+
+func (p *rngPattern) startTagCloseDeriv() *rngPattern {
+	switch p.kind {
+	case rngAfter:
+		return rngAfterOf(p.p1.startTagCloseDeriv(), p.p2)
+	case rngChoice:
+		return rngChoiceOf(p.p1.startTagCloseDeriv(), p.p2.startTagCloseDeriv())
+	case rngGroup, rngInterleave:
+		return rngPair(p.kind, p.p1.startTagCloseDeriv(), p.p2.startTagCloseDeriv())
+	case rngOneOrMore:
+		return rngOneOrMoreOf(p.p1.startTagCloseDeriv())
+	case rngAttribute:
+		return rngNotAllowedPattern
+	}
+	return p
+}
+
+func (p *rngPattern) endTagDeriv() *rngPattern {
+	switch p.kind {
+	case rngChoice:
+		return rngChoiceOf(p.p1.endTagDeriv(), p.p2.endTagDeriv())
+	case rngAfter:
+		if p.p1.nullable() {
+			return p.p2
+		}
+	}
+	return rngNotAllowedPattern
+}
+
+// rngNode is an element or a text of the validated document
+type rngNode struct {
+	name     xml.Name
+	attr     []xml.Attr
+	text     string
+	children []*rngNode
+}
+
+func (p *rngPattern) childDeriv(n *rngNode) *rngPattern {
+	if n.name.Local == "" {
+		return p.textDeriv(n.text)
+	}
+	p = p.startTagOpenDeriv(n.name)
+	for _, a := range n.attr {
+		p = p.attDeriv(a)
+	}
+	p = p.startTagCloseDeriv()
+	var children []*rngNode
+	for _, c := range n.children {
+		if c.name.Local != "" || strings.TrimSpace(c.text) != "" {
+			children = append(children, c)
+		}
+	}
+	if len(children) == 0 {
+		p = rngChoiceOf(p, p.textDeriv(""))
+	}
+	for _, c := range children {
+		p = p.childDeriv(c)
+	}
+	return p.endTagDeriv()
+}
+
+// parseRNGNodes parses XML into a tree; xmlns attributes are removed
+func parseRNGNodes(r io.Reader) (*rngNode, error) {
+	d := xml.NewDecoder(r)
+	root := &rngNode{}
+	stack := []*rngNode{root}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &rngNode{name: t.Name}
+			for _, a := range t.Attr {
+				if a.Name.Space != "xmlns" && a.Name.Local != "xmlns" {
+					n.attr = append(n.attr, a)
+				}
+			}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			top.children = append(top.children, &rngNode{text: string(t)})
+		}
+	}
+	for _, c := range root.children {
+		if c.name.Local != "" {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no root element")
+}
+
+func (n *rngNode) attrValue(name string) string {
+	for _, a := range n.attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// rngSchema is a parsed grammar
+type rngSchema struct {
+	start   *rngPattern
+	defines map[string]*rngPattern
+	ids     map[xml.Name]bool // attributes of ID type
+}
+
+// loadRNG loads a RELAX NG grammar in the simple syntax
+func loadRNG(path string) (*rngSchema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	root, err := parseRNGNodes(f)
+	if err != nil {
+		return nil, err
+	}
+	s := &rngSchema{defines: map[string]*rngPattern{}, ids: map[xml.Name]bool{}}
+	ns := root.attrValue("ns")
+	for _, c := range root.children {
+		if c.name.Local == "define" {
+			s.defines[c.attrValue("name")] = &rngPattern{kind: rngRef}
+		}
+	}
+	for _, c := range root.children {
+		switch c.name.Local {
+		case "start":
+			s.start = s.group(c, ns)
+		case "define":
+			*s.defines[c.attrValue("name")] = *s.group(c, ns)
+		}
+	}
+	for name, p := range s.defines {
+		if p.kind == rngRef {
+			return nil, fmt.Errorf("define %s is not resolved", name)
+		}
+	}
+	return s, nil
+}
+
+// group returns the pattern of the children of n
+func (s *rngSchema) group(n *rngNode, ns string) *rngPattern {
+	p := rngEmptyPattern
+	for _, c := range n.children {
+		if c.name.Local != "" {
+			p = rngPair(rngGroup, p, s.pattern(c, ns))
+		}
+	}
+	return p
+}
+
+func (s *rngSchema) pattern(n *rngNode, ns string) *rngPattern {
+	switch n.name.Local {
+	case "element":
+		return &rngPattern{kind: rngElement, name: xml.Name{Space: ns, Local: n.attrValue("name")}, p1: s.group(n, ns)}
+	case "attribute":
+		name := xml.Name{Local: n.attrValue("name")}
+		if strings.HasPrefix(name.Local, "xml:") {
+			name = xml.Name{Space: "http://www.w3.org/XML/1998/namespace", Local: strings.TrimPrefix(name.Local, "xml:")}
+		}
+		content := s.group(n, ns)
+		if content.kind == rngEmpty {
+			content = rngTextPattern
+		}
+		for _, c := range n.children {
+			if c.name.Local == "data" && c.attrValue("type") == "ID" {
+				s.ids[name] = true
+			}
+		}
+		return &rngPattern{kind: rngAttribute, name: name, p1: content}
+	case "group":
+		return s.group(n, ns)
+	case "choice":
+		p := rngNotAllowedPattern
+		for _, c := range n.children {
+			if c.name.Local != "" {
+				p = rngChoiceOf(p, s.pattern(c, ns))
+			}
+		}
+		return p
+	case "interleave":
+		p := rngEmptyPattern
+		for _, c := range n.children {
+			if c.name.Local != "" {
+				p = rngPair(rngInterleave, p, s.pattern(c, ns))
+			}
+		}
+		return p
+	case "optional":
+		return rngChoiceOf(s.group(n, ns), rngEmptyPattern)
+	case "zeroOrMore":
+		return rngChoiceOf(rngOneOrMoreOf(s.group(n, ns)), rngEmptyPattern)
+	case "oneOrMore":
+		return rngOneOrMoreOf(s.group(n, ns))
+	case "mixed":
+		return rngPair(rngInterleave, s.group(n, ns), rngTextPattern)
+	case "text":
+		return rngTextPattern
+	case "data":
+		return &rngPattern{kind: rngData, match: rngDatatype(n)}
+	case "list":
+		return &rngPattern{kind: rngList, p1: s.group(n, ns)}
+	case "empty":
+		return rngEmptyPattern
+	case "value":
+		var b strings.Builder
+		for _, c := range n.children {
+			b.WriteString(c.text)
+		}
+		return &rngPattern{kind: rngValue, value: strings.Join(strings.Fields(b.String()), " ")}
+	case "ref":
+		return s.defines[n.attrValue("name")]
+	}
+	return rngNotAllowedPattern
+}
+
+var rngNCNameRe = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}._\-]*$`)
+
+// rngDatatype returns the matcher of a data pattern with its pattern params
+func rngDatatype(n *rngNode) func(string) bool {
+	typ := n.attrValue("type")
+	var patterns []*regexp.Regexp
+	for _, c := range n.children {
+		if c.name.Local == "param" && c.attrValue("name") == "pattern" {
+			var b strings.Builder
+			for _, t := range c.children {
+				b.WriteString(t.text)
+			}
+			patterns = append(patterns, regexp.MustCompile("^(?:"+b.String()+")$"))
+		}
+	}
+	return func(v string) bool {
+		if typ != "string" {
+			v = strings.Join(strings.Fields(v), " ")
+		}
+		if (typ == "ID" || typ == "NCName") && !rngNCNameRe.MatchString(v) {
+			return false
+		}
+		for _, re := range patterns {
+			if !re.MatchString(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// validate validates an XML document against the schema
+func (s *rngSchema) validate(r io.Reader) error {
+	root, err := parseRNGNodes(r)
+	if err != nil {
+		return err
+	}
+	if p := s.start.childDeriv(root); !p.nullable() {
+		return fmt.Errorf("document is not valid")
+	}
+	return s.checkIDs(root, map[string]bool{})
+}
+
+// checkIDs checks values of ID attributes in n are not in seen, adding them
+func (s *rngSchema) checkIDs(n *rngNode, seen map[string]bool) error {
+	for _, a := range n.attr {
+		if !s.ids[a.Name] {
+			continue
+		}
+		if seen[a.Value] {
+			return fmt.Errorf("ID %q is duplicated", a.Value)
+		}
+		seen[a.Value] = true
+	}
+	for _, c := range n.children {
+		if err := s.checkIDs(c, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
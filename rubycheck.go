@@ -0,0 +1,168 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RubyIssueKind is a kind of RubyIssue
+type RubyIssueKind int
+
+// Kinds of ruby issue
+const (
+	// RubyConflict is ruby whose reading differs from the other ruby of the same base
+	RubyConflict RubyIssueKind = iota + 1
+	// RubySpan is implicit ruby whose base (the kanji run before `《`) is probably mis-detected
+	RubySpan
+)
+
+// String returns the description of the kind
+func (k RubyIssueKind) String() string {
+	switch k {
+	case RubyConflict:
+		return "conflicting ruby"
+	case RubySpan:
+		return "ruby span"
+	}
+	return "unknown"
+}
+
+// RubyIssue is a problem of ruby found by CheckRuby
+type RubyIssue struct {
+	Line    int // 1-origin line number
+	Kind    RubyIssueKind
+	Base    string
+	Reading string
+	Note    string // other readings, or the suggested notation
+}
+
+// String returns a report line of the issue
+func (i RubyIssue) String() string {
+	return fmt.Sprintf("%d: %v: %s《%s》 %s", i.Line, i.Kind, i.Base, i.Reading, i.Note)
+}
+
+// rubyOccurrence is a ruby pair at a line
+type rubyOccurrence struct {
+	RubyPair
+	line     int
+	implicit bool
+	before   string // the text before the base in the line
+}
+
+// CheckRuby checks ruby in a decoded work (Unicode text in Aozora Bunko format).
+// It reports bases given different readings, and implicit ruby whose base is
+// probably mis-detected: the reading starts with the kana just before the base
+// (`ロンドン塔《ロンドンとう》`), a tail of the base has the same reading elsewhere
+// (`東京都知事《ちじ》` and `｜知事《ちじ》`), or the reading is shorter than the base.
+func CheckRuby(str string) []RubyIssue {
+	var occurrences []rubyOccurrence
+	for i, line := range strings.Split(str, "\n") {
+		for _, m := range rubyPairRe.FindAllStringSubmatchIndex(line, -1) {
+			o := rubyOccurrence{line: i + 1, implicit: m[6] >= 0}
+			var base string
+			if o.implicit {
+				base, o.Reading, o.before = line[m[6]:m[7]], line[m[8]:m[9]], line[:m[6]]
+			} else {
+				base, o.Reading = line[m[2]:m[3]], line[m[4]:m[5]]
+			}
+			var ok bool
+			if o.Base, ok = resolveGaiji(base); !ok || o.Reading == "" || strings.Contains(o.Reading, "［＃") {
+				continue
+			}
+			o.Reading = ToHiragana(o.Reading)
+			occurrences = append(occurrences, o)
+		}
+	}
+
+	var issues []RubyIssue
+	pairs := map[RubyPair]bool{}
+	readings := map[string][]rubyOccurrence{}
+	for _, o := range occurrences {
+		pairs[o.RubyPair] = true
+		readings[o.Base] = append(readings[o.Base], o)
+	}
+	for _, o := range occurrences {
+		if note := rubyConflict(o, readings[o.Base]); note != "" {
+			issues = append(issues, RubyIssue{Line: o.line, Kind: RubyConflict, Base: o.Base, Reading: o.Reading, Note: note})
+		}
+		if note := rubySpan(o, pairs); note != "" {
+			issues = append(issues, RubyIssue{Line: o.line, Kind: RubySpan, Base: o.Base, Reading: o.Reading, Note: note})
+		}
+	}
+	return issues
+}
+
+// rubyConflict returns the other readings of the base if the reading of o
+// is not the most frequent one
+func rubyConflict(o rubyOccurrence, all []rubyOccurrence) string {
+	lines := map[string][]string{}
+	var order []string
+	for _, a := range all {
+		if _, ok := lines[a.Reading]; !ok {
+			order = append(order, a.Reading)
+		}
+		lines[a.Reading] = append(lines[a.Reading], strconv.Itoa(a.line))
+	}
+	if len(order) < 2 {
+		return ""
+	}
+	// the most frequent reading, or the first one if tied
+	major := order[0]
+	for _, r := range order {
+		if len(lines[r]) > len(lines[major]) {
+			major = r
+		}
+	}
+	if o.Reading == major {
+		return ""
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(lines[order[i]]) > len(lines[order[j]]) })
+	var others []string
+	for _, r := range order {
+		if r != o.Reading {
+			others = append(others, fmt.Sprintf("%s (line %s)", r, strings.Join(lines[r], ", ")))
+		}
+	}
+	return "also read as " + strings.Join(others, ", ")
+}
+
+// rubySpan returns the suggested notation if the base of implicit ruby o is probably mis-detected
+func rubySpan(o rubyOccurrence, pairs map[RubyPair]bool) string {
+	if !o.implicit {
+		return ""
+	}
+	// the reading starts with the kana or alphanumerics before the base;
+	// a single character is often a particle like `は`
+	before := []rune(o.before)
+	i := len(before)
+	for i > 0 && isRubyReadingText(before[i-1]) {
+		i--
+	}
+	for ; i < len(before)-1; i++ {
+		if strings.HasPrefix(o.Reading, ToHiragana(string(before[i:]))) {
+			return fmt.Sprintf("may be ｜%s%s《%s》", string(before[i:]), o.Base, o.Reading)
+		}
+	}
+	// a tail of the base has the same reading elsewhere
+	base := []rune(o.Base)
+	for i := 1; i < len(base); i++ {
+		if pairs[RubyPair{Base: string(base[i:]), Reading: o.Reading}] {
+			return fmt.Sprintf("may be %s｜%s《%s》", string(base[:i]), string(base[i:]), o.Reading)
+		}
+	}
+	if utf8.RuneCountInString(o.Reading) < len(base) {
+		return "reading is shorter than the base; the base may need ｜"
+	}
+	return ""
+}
+
+// isRubyReadingText checks r can be a part of a ruby base spelled in the reading
+func isRubyReadingText(r rune) bool {
+	return unicode.In(r, unicode.Hiragana, unicode.Katakana) || r == 'ー' ||
+		('Ａ' <= r && r <= 'Ｚ') || ('ａ' <= r && r <= 'ｚ') || ('０' <= r && r <= '９') ||
+		('A' <= r && r <= 'Z') || ('a' <= r && r <= 'z') || ('0' <= r && r <= '9')
+}
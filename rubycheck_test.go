@@ -0,0 +1,32 @@
+package aozoraconv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckRuby(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want []RubyIssue
+	}{
+		{"何時《いつ》\n何時《なんどき》\n｜何時《イツ》", []RubyIssue{
+			{Line: 2, Kind: RubyConflict, Base: "何時", Reading: "なんどき", Note: "also read as いつ (line 1, 3)"},
+		}},
+		{"ロンドン塔《ロンドンとう》", []RubyIssue{
+			{Line: 1, Kind: RubySpan, Base: "塔", Reading: "ろんどんとう", Note: "may be ｜ロンドン塔《ろんどんとう》"},
+		}},
+		{"｜知事《ちじ》と東京都知事《ちじ》", []RubyIssue{
+			{Line: 1, Kind: RubySpan, Base: "東京都知事", Reading: "ちじ", Note: "may be 東京都｜知事《ちじ》"},
+		}},
+		{"彼女は花《はな》を見た。一寸《ちょっと》鬼ヶ島《おにがしま》", nil},
+		{"大日本帝国《にほん》", []RubyIssue{
+			{Line: 1, Kind: RubySpan, Base: "大日本帝国", Reading: "にほん", Note: "reading is shorter than the base; the base may need ｜"},
+		}},
+	}
+	for _, tc := range testcases {
+		if got := CheckRuby(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("CheckRuby(%q) got: %v want: %v", tc.in, got, tc.want)
+		}
+	}
+}
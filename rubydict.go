@@ -0,0 +1,136 @@
+package aozoraconv
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// rubyPairRe matches explicit ruby (`｜base《reading》`) or implicit ruby
+// of the kanji (and gaiji) run before `《`
+var rubyPairRe = regexp.MustCompile(`｜([^｜《》\n]+)《([^《》\n]*)》|((?:※［＃[^［］\n]*］|[\p{Han}々仝〆〇ヶ])+)《([^《》\n]*)》`)
+
+// RubyPair is a ruby base and its reading
+type RubyPair struct {
+	Base    string `json:"base"`
+	Reading string `json:"reading"`
+}
+
+// RubyEntry is a ruby pair with its frequency and sources like "file.txt:12"
+type RubyEntry struct {
+	RubyPair
+	Count   int      `json:"count"`
+	Sources []string `json:"sources"`
+}
+
+// RubyDict is a dictionary of ruby pairs collected from works
+type RubyDict struct {
+	entries map[RubyPair]*RubyEntry
+}
+
+// NewRubyDict returns empty RubyDict
+func NewRubyDict() *RubyDict {
+	return &RubyDict{entries: map[RubyPair]*RubyEntry{}}
+}
+
+// Add adds ruby pairs of a decoded work (Unicode text in Aozora Bunko format);
+// source is the name of the work used in the references
+func (d *RubyDict) Add(str, source string) {
+	for i, line := range strings.Split(str, "\n") {
+		for _, p := range ExtractRuby(line) {
+			e, ok := d.entries[p]
+			if !ok {
+				e = &RubyEntry{RubyPair: p}
+				d.entries[p] = e
+			}
+			e.Count++
+			ref := source + ":" + strconv.Itoa(i+1)
+			if n := len(e.Sources); n == 0 || e.Sources[n-1] != ref {
+				e.Sources = append(e.Sources, ref)
+			}
+		}
+	}
+}
+
+// Entries returns entries in descending order of frequency
+func (d *RubyDict) Entries() []RubyEntry {
+	ret := make([]RubyEntry, 0, len(d.entries))
+	for _, e := range d.entries {
+		ret = append(ret, *e)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		if ret[i].Base != ret[j].Base {
+			return ret[i].Base < ret[j].Base
+		}
+		return ret[i].Reading < ret[j].Reading
+	})
+	return ret
+}
+
+// ExtractRuby returns ruby pairs in str. Gaiji in bases are resolved into characters,
+// and readings are normalized into hiragana. Pairs with unresolvable gaiji,
+// annotations or empty readings are skipped.
+func ExtractRuby(str string) []RubyPair {
+	var ret []RubyPair
+	for _, m := range rubyPairRe.FindAllStringSubmatch(str, -1) {
+		base, reading := m[1], m[2]
+		if m[3] != "" {
+			base, reading = m[3], m[4]
+		}
+		base, ok := resolveGaiji(base)
+		if !ok || reading == "" || strings.Contains(reading, "［＃") {
+			continue
+		}
+		ret = append(ret, RubyPair{Base: base, Reading: ToHiragana(reading)})
+	}
+	return ret
+}
+
+// resolveGaiji replaces gaiji annotations in str with their characters;
+// ok is false if str has other annotations or unresolvable gaiji
+func resolveGaiji(str string) (ret string, ok bool) {
+	ok = true
+	ret = gaijiAnnotationRe.ReplaceAllStringFunc(str, func(s string) string {
+		g, err := ParseGaiji(s)
+		if err != nil || g.Char == "" {
+			ok = false
+		}
+		return g.Char
+	})
+	if strings.Contains(ret, "［＃") {
+		ok = false
+	}
+	return ret, ok
+}
+
+// ToHiragana converts katakana (including half-width one) in str into hiragana
+func ToHiragana(str string) string {
+	for _, r := range str {
+		if isHalfwidthKana(r) {
+			str = widenKana(str)
+			break
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'ァ' <= r && r <= 'ヶ', r == 'ヽ', r == 'ヾ':
+			return r - 0x60
+		}
+		return r
+	}, str)
+}
+
+// IsHiragana checks str consists of hiragana and `ー`
+func IsHiragana(str string) bool {
+	for _, r := range str {
+		if !unicode.Is(unicode.Hiragana, r) && r != 'ー' {
+			return false
+		}
+	}
+	return str != ""
+}
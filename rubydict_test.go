@@ -0,0 +1,62 @@
+package aozoraconv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractRuby(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want []RubyPair
+	}{
+		{"吾輩《わがはい》は猫である", []RubyPair{{"吾輩", "わがはい"}}},
+		{"この｜猫である《ネコデアル》と山々《やまやま》", []RubyPair{{"猫である", "ねこである"}, {"山々", "やまやま"}}},
+		{"※［＃「てへん＋劣」、第3水準1-84-77］《もが》く", []RubyPair{{"挘", "もが"}}},
+		{"｜※［＃「口＋世」、U+546D］語《ｾﾞｺﾞ》", []RubyPair{{"呭語", "ぜご"}}},
+		{"※［＃「さんずい＋不明」］水《みず》", nil},
+		{"漢字《》と｜記号《［＃注記］》", nil},
+		{"ひらがな《かな》", nil},
+	}
+	for _, tc := range testcases {
+		if got := ExtractRuby(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ExtractRuby(%q) got: %v want: %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRubyDict(t *testing.T) {
+	d := NewRubyDict()
+	d.Add("何時《いつ》\n何時《なんどき》と何時《いつ》\n", "a.txt")
+	d.Add("何時《イツ》か", "b.txt")
+	want := []RubyEntry{
+		{RubyPair{"何時", "いつ"}, 3, []string{"a.txt:1", "a.txt:2", "b.txt:1"}},
+		{RubyPair{"何時", "なんどき"}, 1, []string{"a.txt:2"}},
+	}
+	if got := d.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RubyDict.Entries got: %v want: %v", got, want)
+	}
+}
+
+func TestToHiragana(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want string
+	}{
+		{"カタカナ", "かたかな"},
+		{"ヴァイオリン", "ゔぁいおりん"},
+		{"ｶﾞｯｺｳ", "がっこう"},
+		{"ひらがなー", "ひらがなー"},
+		{"ヽヾ", "ゝゞ"},
+	}
+	for _, tc := range testcases {
+		if got := ToHiragana(tc.in); got != tc.want {
+			t.Errorf("ToHiragana(%q) got: %q want: %q", tc.in, got, tc.want)
+		}
+	}
+	for in, want := range map[string]bool{"かな": true, "かなー": true, "カナ": false, "light": false, "": false} {
+		if got := IsHiragana(in); got != want {
+			t.Errorf("IsHiragana(%q) got: %v want: %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package aozoraconv
+
+import (
+	"unicode/utf8"
+)
+
+// Segmenter walks a string by logical characters of JIS X 0213.
+// Combining sequences in JIS X 0213 (like `か` + U+309A) are matched greedily
+// as one character.
+//
+//	s := NewSegmenter(str)
+//	for s.Next() {
+//		text, code, ok := s.Segment()
+//		...
+//	}
+type Segmenter struct {
+	str  string
+	pos  int
+	text string
+	code JISCode
+	ok   bool
+}
+
+// NewSegmenter returns a Segmenter reading from str
+func NewSegmenter(str string) *Segmenter {
+	return &Segmenter{str: str}
+}
+
+// Next advances to the next character. It returns false at the end of the string.
+func (s *Segmenter) Next() bool {
+	rest := s.str[s.pos:]
+	if rest == "" {
+		s.text, s.code, s.ok = "", JISCode{}, false
+		return false
+	}
+	_, n := utf8.DecodeRuneInString(rest)
+	if _, n2 := utf8.DecodeRuneInString(rest[n:]); n2 > 0 {
+		if jis, err := Uni2Jis(rest[:n+n2]); err == nil {
+			s.advance(n+n2, jis, true)
+			return true
+		}
+	}
+	jis, err := Uni2Jis(rest[:n])
+	s.advance(n, jis, err == nil)
+	return true
+}
+
+func (s *Segmenter) advance(n int, code JISCode, ok bool) {
+	s.text = s.str[s.pos : s.pos+n]
+	s.code, s.ok = code, ok
+	s.pos += n
+}
+
+// Segment returns the current character, its JIS code and whether the character
+// is in JIS X 0213. ASCII characters are not treated as JIS X 0213.
+func (s *Segmenter) Segment() (text string, code JISCode, ok bool) {
+	return s.text, s.code, s.ok
+}
+
+// Offset returns the byte offset of the next character in the string
+func (s *Segmenter) Offset() int {
+	return s.pos
+}
@@ -0,0 +1,44 @@
+package aozoraconv
+
+import (
+	"testing"
+)
+
+func TestSegmenter(t *testing.T) {
+	type segment struct {
+		text string
+		code JISCode
+		ok   bool
+	}
+	var convertedPairs = []struct {
+		in  string
+		out []segment
+	}{
+		{"", nil},
+		{"か゚き", []segment{{"か゚", JISCode{1, 4, 87}, true}, {"き", JISCode{1, 4, 13}, true}}},
+		{"ㇷ゚", []segment{{"ㇷ゚", JISCode{1, 6, 88}, true}}},
+		{"あ゚", []segment{{"あ", JISCode{1, 4, 2}, true}, {"゚", JISCode{}, false}}},
+		{"A☺𠂉", []segment{{"A", JISCode{}, false}, {"☺", JISCode{}, false}, {"𠂉", JISCode{2, 1, 1}, true}}},
+		{"\xff亜", []segment{{"\xff", JISCode{}, false}, {"亜", JISCode{1, 16, 1}, true}}},
+	}
+	for _, tt := range convertedPairs {
+		var got []segment
+		s := NewSegmenter(tt.in)
+		for s.Next() {
+			text, code, ok := s.Segment()
+			got = append(got, segment{text, code, ok})
+		}
+		if len(got) != len(tt.out) {
+			t.Errorf("Segmenter(%q) got: %v want: %v", tt.in, got, tt.out)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.out[i] {
+				t.Errorf("Segmenter(%q) got: %v want: %v", tt.in, got[i], tt.out[i])
+			}
+		}
+		if s.Offset() != len(tt.in) {
+			t.Errorf("Segmenter(%q).Offset got: %v want: %v", tt.in, s.Offset(), len(tt.in))
+		}
+	}
+}
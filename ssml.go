@@ -0,0 +1,179 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SSMLOptions are options of SSML
+type SSMLOptions struct {
+	// MaxBytes is the maximum size of a chunk in bytes; 0 is no limit
+	MaxBytes int
+}
+
+const (
+	ssmlHeader = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+		"<speak version=\"1.1\" xmlns=\"http://www.w3.org/2001/10/synthesis\" xml:lang=\"ja-JP\">\n"
+	ssmlFooter = "</speak>\n"
+)
+
+// Pauses in milliseconds: before headings of each level, after headings, and at page breaks
+var (
+	ssmlHeadingBreaks = map[int]int{1: 1500, 2: 1000, 3: 700}
+	ssmlAfterHeading  = 500
+	ssmlPageBreak     = 2000
+)
+
+// SSML converts doc into SSML documents for text-to-speech, split into chunks of
+// opts.MaxBytes. Ruby is `<sub alias="かんじ">漢字</sub>`, headings and page breaks
+// are `<break>`s, and annotations are not spoken. Gaiji are the characters or their descriptions.
+// It returns an error if MaxBytes is too small for a character, a ruby or a break.
+func SSML(doc *Document, opts SSMLOptions) ([]string, error) {
+	limit := 0
+	if opts.MaxBytes < 0 {
+		return nil, fmt.Errorf("invalid MaxBytes: %d", opts.MaxBytes)
+	}
+	if opts.MaxBytes > 0 {
+		limit = opts.MaxBytes - len(ssmlHeader) - len(ssmlFooter)
+		if limit <= len(ssmlBreak(ssmlPageBreak)) {
+			return nil, fmt.Errorf("MaxBytes %d is too small for SSML", opts.MaxBytes)
+		}
+	}
+	var units []string
+	for _, s := range []string{doc.Title, doc.Author} {
+		if s != "" {
+			units = append(units, ssmlParagraph([][]string{ssmlAtoms(s)}, limit)...)
+		}
+	}
+	if len(units) > 0 {
+		units = append(units, ssmlBreak(ssmlPageBreak))
+	}
+	units = ssmlBlocks(units, doc.Blocks, limit)
+
+	var chunks []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			chunks = append(chunks, ssmlHeader+b.String()+ssmlFooter)
+			b.Reset()
+		}
+	}
+	for _, u := range units {
+		if limit > 0 && len(u) > limit {
+			return nil, fmt.Errorf("MaxBytes %d is too small for SSML: %q", opts.MaxBytes, u)
+		}
+		if limit > 0 && b.Len()+len(u) > limit {
+			flush()
+		}
+		b.WriteString(u)
+	}
+	flush()
+	return chunks, nil
+}
+
+func ssmlBlocks(units []string, blocks []Block, limit int) []string {
+	for _, bl := range blocks {
+		switch bl.Kind {
+		case BlockIndent, BlockBottom:
+			units = ssmlBlocks(units, bl.Blocks, limit)
+		case BlockPageBreak:
+			units = append(units, ssmlBreak(ssmlPageBreak))
+		case BlockHeading:
+			units = append(units, ssmlBreak(ssmlHeadingBreaks[bl.Level]))
+			units = append(units, ssmlParagraph(ssmlSentences(bl.Inlines), limit)...)
+			units = append(units, ssmlBreak(ssmlAfterHeading))
+		default:
+			units = append(units, ssmlParagraph(ssmlSentences(bl.Inlines), limit)...)
+		}
+	}
+	return units
+}
+
+func ssmlBreak(ms int) string {
+	return fmt.Sprintf("<break time=\"%dms\"/>\n", ms)
+}
+
+// ssmlParagraph packs sentences into `<p>`s within limit. A sentence is a list of
+// atoms (escaped characters or `<sub>`s), and is split between atoms if it is too long.
+func ssmlParagraph(sentences [][]string, limit int) []string {
+	const start, end = "<p>", "</p>\n"
+	if limit > 0 {
+		limit -= len(start) + len(end)
+	}
+	var units []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			units = append(units, start+b.String()+end)
+			b.Reset()
+		}
+	}
+	for _, atoms := range sentences {
+		s := strings.Join(atoms, "")
+		if limit <= 0 || b.Len()+len(s) <= limit {
+			b.WriteString(s)
+			continue
+		}
+		flush()
+		if len(s) <= limit {
+			b.WriteString(s)
+			continue
+		}
+		for _, a := range atoms {
+			if b.Len()+len(a) > limit {
+				flush()
+			}
+			b.WriteString(a)
+		}
+	}
+	flush()
+	return units
+}
+
+// ssmlSentences splits spoken inlines into sentences of atoms
+func ssmlSentences(inlines []Inline) [][]string {
+	var sentences [][]string
+	var cur []string
+	var walk func([]Inline)
+	addText := func(s string) {
+		for _, r := range s {
+			cur = append(cur, xmlEscaper.Replace(string(r)))
+			if strings.ContainsRune("。！？!?", r) {
+				sentences = append(sentences, cur)
+				cur = nil
+			}
+		}
+	}
+	walk = func(inlines []Inline) {
+		for _, in := range inlines {
+			switch in.Kind {
+			case InlineText:
+				addText(in.Text)
+			case InlineRuby:
+				cur = append(cur, fmt.Sprintf("<sub alias=\"%s\">%s</sub>", xmlEscaper.Replace(in.Ruby), xmlEscaper.Replace(in.Text)))
+			case InlineEmphasis, InlineTateChuYoko:
+				walk(in.Children)
+			case InlineGaiji:
+				if in.Text != "" {
+					addText(in.Text)
+				} else if g, err := ParseGaiji("※［＃" + in.Annotation + "］"); err == nil {
+					addText(g.Description)
+				}
+			}
+		}
+	}
+	walk(inlines)
+	if len(cur) > 0 {
+		sentences = append(sentences, cur)
+	}
+	return sentences
+}
+
+// ssmlAtoms returns escaped characters of s
+func ssmlAtoms(s string) []string {
+	var atoms []string
+	for _, r := range s {
+		atoms = append(atoms, xmlEscaper.Replace(string(r)))
+	}
+	return atoms
+}
@@ -0,0 +1,104 @@
+package aozoraconv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSSML(t *testing.T) {
+	chunks, err := SSML(ParseDocument(documentSample), SSMLOptions{})
+	if err != nil {
+		t.Fatalf("SSML error: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("SSML got %d chunks want 1", len(chunks))
+	}
+	want := ssmlHeader +
+		"<p>吾輩は猫である</p>\n<p>夏目漱石</p>\n<break time=\"2000ms\"/>\n" +
+		"<break time=\"1500ms\"/>\n<p>一</p>\n<break time=\"500ms\"/>\n" +
+		"<p>　<sub alias=\"わがはい\">吾輩</sub>は<sub alias=\"ねこである\">猫である</sub>。</p>\n" +
+		"<p>じめじめした所で挘いた。</p>\n" +
+		"<p>明治３８年</p>\n" +
+		"<p>強調</p>\n" +
+		"<break time=\"2000ms\"/>\n" +
+		"<p>夏目</p>\n" +
+		ssmlFooter
+	if chunks[0] != want {
+		t.Errorf("SSML got:\n%s\nwant:\n%s", chunks[0], want)
+	}
+}
+
+func TestSSMLGaiji(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want string
+	}{
+		{"※［＃「てへん＋劣」、第3水準1-84-77］", "挘"},
+		{"※［＃「さんずい＋不明」］", "さんずい＋不明"},
+		{"<&>［＃注記］", "&lt;&amp;&gt;"},
+	}
+	for _, tc := range testcases {
+		inlines, _ := parseInlines(tc.in)
+		var got string
+		for _, s := range ssmlSentences(inlines) {
+			got += strings.Join(s, "")
+		}
+		if got != tc.want {
+			t.Errorf("ssmlSentences(%q) got: %q want: %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSSMLChunks(t *testing.T) {
+	doc := ParseDocument("題\n\n" + strings.Repeat("あいうえお。", 20) + "\n" + strings.Repeat("か", 100) + "\n｜漢字《かんじ》\n")
+	const max = 300
+	chunks, err := SSML(doc, SSMLOptions{MaxBytes: max})
+	if err != nil {
+		t.Fatalf("SSML error: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("SSML got %d chunks want several", len(chunks))
+	}
+	var text string
+	for _, c := range chunks {
+		if len(c) > max {
+			t.Errorf("SSML chunk is %d bytes, larger than %d:\n%s", len(c), max, c)
+		}
+		if !strings.HasPrefix(c, ssmlHeader) || !strings.HasSuffix(c, ssmlFooter) {
+			t.Errorf("SSML chunk is not a SSML document:\n%s", c)
+		}
+		text += c
+	}
+	for _, want := range []string{"<p>あいうえお。", "<sub alias=\"かんじ\">漢字</sub>"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("SSML chunks have no %q", want)
+		}
+	}
+	plain := strings.NewReplacer(ssmlHeader, "", ssmlFooter, "", "<p>", "", "</p>\n", "", "<break time=\"2000ms\"/>\n", "").Replace(text)
+	if want := "題" + strings.Repeat("あいうえお。", 20) + strings.Repeat("か", 100) + "<sub alias=\"かんじ\">漢字</sub>"; plain != want {
+		t.Errorf("SSML chunks got text: %q want: %q", plain, want)
+	}
+}
+
+func TestSSMLMaxBytes(t *testing.T) {
+	doc := ParseDocument("題\n\n" + strings.Repeat("あいうえお。", 20) + "\n｜漢字《かんじ》\n")
+	for max := 180; max <= 400; max += 10 {
+		chunks, err := SSML(doc, SSMLOptions{MaxBytes: max})
+		if err != nil {
+			t.Fatalf("SSML(MaxBytes: %d) error: %v", max, err)
+		}
+		if len(chunks) < 2 {
+			t.Errorf("SSML(MaxBytes: %d) got %d chunks want several", max, len(chunks))
+		}
+		for _, c := range chunks {
+			if len(c) > max {
+				t.Errorf("SSML(MaxBytes: %d) chunk is %d bytes:\n%s", max, len(c), c)
+			}
+		}
+	}
+	for _, max := range []int{-1, 100, 150, 170} {
+		if _, err := SSML(doc, SSMLOptions{MaxBytes: max}); err == nil {
+			t.Errorf("SSML(MaxBytes: %d) got no error", max)
+		}
+	}
+}
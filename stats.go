@@ -0,0 +1,142 @@
+package aozoraconv
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Manuscript paper (原稿用紙) has 20 columns and 20 rows
+const (
+	ManuscriptColumns = 20
+	ManuscriptRows    = 20
+)
+
+var (
+	rubyRe       = regexp.MustCompile(`《[^》]*》`)
+	unresolvable = "\uFFFD"
+)
+
+// Stats is character statistics of a work or a corpus
+type Stats struct {
+	Files       int `json:"files"`
+	Characters  int `json:"characters"` // excluding ruby, annotations and line breaks
+	Distinct    int `json:"distinct"`
+	ASCII       int `json:"ascii"`
+	NonKanji    int `json:"non_kanji"` // non-kanji characters in JIS X 0213
+	Level1      int `json:"level1"`
+	Level2      int `json:"level2"`
+	Level3      int `json:"level3"`
+	Level4      int `json:"level4"`
+	UnicodeOnly int `json:"unicode_only"` // characters not in JIS X 0213
+	Unresolved  int `json:"unresolved"`   // gaiji annotations without Unicode nor JIS code
+
+	Gaiji            []string `json:"gaiji"`              // gaiji annotations
+	UnicodeOnlyChars []string `json:"unicode_only_chars"` // distinct characters not in JIS X 0213
+
+	Ruby  int `json:"ruby"`  // number of ruby
+	Pages int `json:"pages"` // pages of 400-character manuscript paper
+
+	freq map[string]int
+}
+
+// CharFreq is a frequency of a character
+type CharFreq struct {
+	Char  string `json:"char"`
+	Count int    `json:"count"`
+	Level int    `json:"level"`
+	Code  string `json:"code,omitempty"`
+}
+
+// NewStats returns empty Stats
+func NewStats() *Stats {
+	return &Stats{freq: map[string]int{}}
+}
+
+// Add adds statistics of a decoded work (Unicode text in Aozora Bunko format)
+func (s *Stats) Add(str string) {
+	s.Files++
+	str = gaijiAnnotationRe.ReplaceAllStringFunc(str, func(annotation string) string {
+		s.Gaiji = append(s.Gaiji, annotation)
+		g, err := ParseGaiji(annotation)
+		if err != nil || g.Char == "" {
+			return unresolvable
+		}
+		return g.Char
+	})
+	str = annotationRe.ReplaceAllString(str, "")
+	s.Ruby += len(rubyRe.FindAllStringIndex(str, -1))
+	str = rubyRe.ReplaceAllString(str, "")
+	str = strings.Replace(str, "｜", "", -1)
+
+	rows := 0
+	for _, line := range strings.Split(ConvLineEnding(str, EOLLF), "\n") {
+		n := 0
+		for _, c := range ClassifyString(line) {
+			n++
+			s.add(c)
+		}
+		rows += (n + ManuscriptColumns - 1) / ManuscriptColumns
+		if n == 0 {
+			rows++
+		}
+	}
+	s.Pages += (rows + ManuscriptRows - 1) / ManuscriptRows
+	s.Distinct = len(s.freq)
+}
+
+func (s *Stats) add(c CharClass) {
+	s.Characters++
+	if c.Char == unresolvable {
+		s.Unresolved++
+		return
+	}
+	if s.freq[c.Char] == 0 && !c.In0213 && !(len(c.Char) == 1 && c.Char[0] < utf8.RuneSelf) {
+		s.UnicodeOnlyChars = append(s.UnicodeOnlyChars, c.Char)
+	}
+	s.freq[c.Char]++
+	switch {
+	case len(c.Char) == 1 && c.Char[0] < utf8.RuneSelf:
+		s.ASCII++
+	case !c.In0213:
+		s.UnicodeOnly++
+	case c.Level == 1:
+		s.Level1++
+	case c.Level == 2:
+		s.Level2++
+	case c.Level == 3:
+		s.Level3++
+	case c.Level == 4:
+		s.Level4++
+	default:
+		s.NonKanji++
+	}
+}
+
+// RubyDensity returns the number of ruby per 1000 characters
+func (s *Stats) RubyDensity() float64 {
+	if s.Characters == 0 {
+		return 0
+	}
+	return float64(s.Ruby) * 1000 / float64(s.Characters)
+}
+
+// Frequency returns the frequency table of characters, sorted by decreasing count
+func (s *Stats) Frequency() []CharFreq {
+	ret := make([]CharFreq, 0, len(s.freq))
+	for chr, n := range s.freq {
+		f := CharFreq{Char: chr, Count: n}
+		if c := classify(chr); c.In0213 {
+			f.Level, f.Code = c.Level, c.Code.String()
+		}
+		ret = append(ret, f)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Char < ret[j].Char
+	})
+	return ret
+}
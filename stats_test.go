@@ -0,0 +1,99 @@
+package aozoraconv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	text := "［＃３字下げ］第一章［＃「第一章」は大見出し］\r\n" +
+		"｜東京《とうきょう》の亜※［＃「にんべん＋乂」、第4水準2-1-1、1-2］☺A\r\n" +
+		"\r\n" +
+		"弌※［＃「口＋世」、1-3］\r\n"
+	s := NewStats()
+	s.Add(text)
+
+	var convertedPairs = []struct {
+		name      string
+		got, want int
+	}{
+		{"Files", s.Files, 1},
+		{"Characters", s.Characters, 12},
+		{"Distinct", s.Distinct, 11},
+		{"ASCII", s.ASCII, 1},
+		{"NonKanji", s.NonKanji, 1},
+		{"Level1", s.Level1, 6},
+		{"Level2", s.Level2, 1},
+		{"Level3", s.Level3, 0},
+		{"Level4", s.Level4, 1},
+		{"UnicodeOnly", s.UnicodeOnly, 1},
+		{"Unresolved", s.Unresolved, 1},
+		{"Gaiji", len(s.Gaiji), 2},
+		{"Ruby", s.Ruby, 1},
+		{"Pages", s.Pages, 1},
+	}
+	for _, tt := range convertedPairs {
+		if tt.got != tt.want {
+			t.Errorf("Stats.%s got: %v want: %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if len(s.UnicodeOnlyChars) != 1 || s.UnicodeOnlyChars[0] != "☺" {
+		t.Errorf("Stats.UnicodeOnlyChars got: %v", s.UnicodeOnlyChars)
+	}
+
+	s.Add("亜亜")
+	if s.Files != 2 || s.Characters != 14 || s.Distinct != 11 || s.Pages != 2 {
+		t.Errorf("Stats.Add got: %+v", s)
+	}
+	freq := s.Frequency()
+	if freq[0].Char != "亜" || freq[0].Count != 3 || freq[0].Level != 1 || freq[0].Code != "1-16-1" {
+		t.Errorf("Stats.Frequency got: %+v", freq[0])
+	}
+	if got, want := s.RubyDensity(), 1000.0/14; got != want {
+		t.Errorf("Stats.RubyDensity got: %v want: %v", got, want)
+	}
+}
+
+func TestStatsPages(t *testing.T) {
+	var convertedPairs = []struct {
+		in    string
+		pages int
+	}{
+		{"", 1},
+		{strings.Repeat("あ", 400), 1},
+		{strings.Repeat("あ", 401), 2},
+		{strings.Repeat("あ\n", 20), 2},
+		{strings.Repeat("あ\n", 19), 1},
+		{strings.Repeat("あ", 21) + "\n" + strings.Repeat("い\n", 18), 2},
+	}
+	for _, tt := range convertedPairs {
+		s := NewStats()
+		s.Add(tt.in)
+		if s.Pages != tt.pages {
+			t.Errorf("Stats.Pages got: %v want: %v", s.Pages, tt.pages)
+		}
+	}
+}
+
+func TestParseGaiji(t *testing.T) {
+	var convertedPairs = []struct {
+		in        string
+		out       Gaiji
+		isSuccess bool
+	}{
+		{"※［＃「口＋七」、第3水準1-47-52、123-4］", Gaiji{"口＋七", JISCode{1, 47, 52}, "𠮟", "123-4"}, true},
+		{"［＃「口＋七」、U+20B9F、12-上-4］", Gaiji{"口＋七", JISCode{}, "𠮟", "12-上-4"}, true},
+		{"※［＃ローマ数字1、1-13-21］", Gaiji{"ローマ数字1", JISCode{1, 13, 21}, "Ⅰ", ""}, true},
+		{"※［＃「口＋世」、1-3］", Gaiji{"口＋世", JISCode{}, "", "1-3"}, true},
+		{"※「口＋世」", Gaiji{}, false},
+	}
+	for _, tt := range convertedPairs {
+		got, err := ParseGaiji(tt.in)
+		if (err == nil) != tt.isSuccess {
+			t.Errorf("ParseGaiji(%v) error: %v", tt.in, err)
+		}
+		if got != tt.out {
+			t.Errorf("ParseGaiji(%v) got: %+v want: %+v", tt.in, got, tt.out)
+		}
+	}
+}
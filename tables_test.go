@@ -1,6 +1,13 @@
 package aozoraconv
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -17,25 +24,193 @@ func TestJis2013Decode(t *testing.T) {
 		{2, 1, 1, "𠂉"},
 	}
 	for _, tt := range convertedStrings {
-		if got, want := jis0213Decode[tt.men-1][tt.ku-1][tt.ten-1], tt.c; got != want {
+		if got, want := decodeJis(tt.men, tt.ku, tt.ten), tt.c; got != want {
 			t.Errorf("jis0213Decode: got %v want %v", got, want)
 		}
 	}
 }
 
-func TestEncode1(t *testing.T) {
+func TestEncodeRune(t *testing.T) {
 	var codes = []struct {
-		in           uint16
+		in           rune
 		men, ku, ten int
+		ok           bool
 	}{
-		{0x3000, 1, 1, 1},
-		{0x3006, 1, 1, 26},
-		{0x3042, 1, 4, 2},
+		{0x3000, 1, 1, 1, true},
+		{0x3006, 1, 1, 26, true},
+		{0x3042, 1, 4, 2, true},
+		{0x2000B, 1, 14, 2, true},
+		{0x20089, 2, 1, 1, true},
+		{0x263A, 0, 0, 0, false},
+		{0x10FFFF, 0, 0, 0, false},
+		{-1, 0, 0, 0, false},
 	}
 	for _, tt := range codes {
-		got, want := encode1[tt.in-encode1Low], uint16(tt.men<<14|tt.ku<<7|tt.ten)
-		if got != want {
-			t.Errorf("encode1: got %v want %v", got, want)
+		got, ok := encodeRune(tt.in)
+		if want := (JISCode{int8(tt.men), int8(tt.ku), int8(tt.ten)}); got != want || ok != tt.ok {
+			t.Errorf("encodeRune(%U): got %v, %v want %v, %v", tt.in, got, ok, want, tt.ok)
 		}
 	}
 }
+
+func TestLookupPair(t *testing.T) {
+	for i, p := range jis0213Pairs {
+		if i > 0 {
+			q := jis0213Pairs[i-1]
+			if q.r1 > p.r1 || (q.r1 == p.r1 && q.r2 >= p.r2) {
+				t.Errorf("jis0213Pairs is not sorted at %d", i)
+			}
+		}
+		if got, ok := lookupPair(jis0213Pairs[:], p.r1, p.r2); !ok || got != p.code {
+			t.Errorf("lookupPair(%U, %U) got: %v want: %v", p.r1, p.r2, got, p.code)
+		}
+	}
+	if _, ok := lookupPair(jis0213Pairs[:], 'か', 'か'); ok {
+		t.Errorf("lookupPair should fail")
+	}
+}
+
+func TestJis2013DecodeGaps(t *testing.T) {
+	var convertedStrings = []struct {
+		men, ku, ten int
+		c            string
+	}{
+		{1, 8, 62, "㊿"},
+		{1, 8, 63, ""},
+		{1, 8, 71, "◐"},
+		{1, 12, 93, "⁑"},
+		{1, 13, 63, "㍻"},
+		{1, 13, 64, "〝"},
+		{1, 13, 82, ""},
+		{1, 13, 83, "∮"},
+		{1, 13, 94, "☞"},
+	}
+	for _, tt := range convertedStrings {
+		if got, want := decodeJis(tt.men, tt.ku, tt.ten), tt.c; got != want {
+			t.Errorf("jis0213Decode %v-%v-%v: got %q want %q", tt.men, tt.ku, tt.ten, got, want)
+		}
+	}
+}
+
+// TestJis2013DecodeEncode checks the decoding table agrees with the encoding tables
+func TestJis2013DecodeEncode(t *testing.T) {
+	for men := 1; men <= 2; men++ {
+		for ku := 1; ku <= 94; ku++ {
+			for ten := 1; ten <= 94; ten++ {
+				chr := decodeJis(men, ku, ten)
+				if chr == "" || chr[0] < 0x7f {
+					// Uni2Jis does not accept ASCII
+					continue
+				}
+				got, err := Uni2Jis(chr)
+				if want := (JISCode{int8(men), int8(ku), int8(ten)}); err != nil || got != want {
+					t.Errorf("Uni2Jis(%q) got: %v want: %v (%v)", chr, got, want, err)
+				}
+			}
+		}
+	}
+}
+
+var benchText = []rune("吾輩は猫である。名前はまだ無い。どこで生れたかとんと見当がつかぬ。𠮟俱①ａＡ～")
+
+func BenchmarkUni2Jis(b *testing.B) {
+	chars := make([]string, len(benchText))
+	for i, r := range benchText {
+		chars[i] = string(r)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, chr := range chars {
+			Uni2Jis(chr)
+		}
+	}
+}
+
+func BenchmarkUni2JisCombining(b *testing.B) {
+	chars := []string{"か゚", "ㇷ゚", "æ̀", "˥˩"}
+	for i := 0; i < b.N; i++ {
+		for _, chr := range chars {
+			Uni2Jis(chr)
+		}
+	}
+}
+
+func BenchmarkJis2Uni(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for ku := 1; ku <= 94; ku++ {
+			Jis2Uni(1, ku, ku)
+		}
+	}
+}
+
+// TestLookupAllocs checks lookups do not allocate; the tables are arrays and
+// constant strings, so they need no heap at init either
+func TestLookupAllocs(t *testing.T) {
+	lookups := map[string]func(){
+		"Uni2Jis":   func() { Uni2Jis("𠮟") },
+		"pair":      func() { Uni2Jis("か゚") },
+		"fullwidth": func() { Uni2Jis("～") },
+		"Jis2Uni":   func() { Jis2Uni(1, 47, 52) },
+	}
+	for name, f := range lookups {
+		if n := testing.AllocsPerRun(100, f); n != 0 {
+			t.Errorf("%s allocates %v times per lookup", name, n)
+		}
+	}
+}
+
+var initTraceRe = regexp.MustCompile(`init github\.com/takahashim/aozoraconv @\S+ ms, \S+ ms clock, (\d+) bytes, (\d+) allocs`)
+
+// BenchmarkTableFootprint builds testdata/tables and reports the size of the
+// binary, the size of the tables linked into it, and the heap allocated by the
+// initialization of the package (GODEBUG=inittrace=1) on each run
+func BenchmarkTableFootprint(b *testing.B) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		b.Skip("go command is not found")
+	}
+	dir, err := ioutil.TempDir("", "aozoraconv")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bin := filepath.Join(dir, "tables")
+	if out, err := exec.Command(goCmd, "build", "-o", bin, "./testdata/tables").CombinedOutput(); err != nil {
+		b.Fatalf("go build error: %v\n%s", err, out)
+	}
+	fi, err := os.Stat(bin)
+	if err != nil {
+		b.Fatal(err)
+	}
+	out, err := exec.Command(goCmd, "tool", "nm", "-size", bin).Output()
+	if err != nil {
+		b.Fatalf("go tool nm error: %v", err)
+	}
+	// jis0213Chars is a constant, which has no symbol of its own
+	tableBytes := len(jis0213Chars)
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 4 && strings.HasPrefix(f[3], "github.com/takahashim/aozoraconv.jis0213") {
+			n, _ := strconv.Atoi(f[1])
+			tableBytes += n
+		}
+	}
+
+	var initBytes, initAllocs int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cmd := exec.Command(bin)
+		cmd.Env = append(os.Environ(), "GODEBUG=inittrace=1")
+		out, err := cmd.CombinedOutput()
+		m := initTraceRe.FindSubmatch(out)
+		if err != nil || m == nil {
+			b.Fatalf("no init trace of the package: %v\n%s", err, out)
+		}
+		initBytes, _ = strconv.Atoi(string(m[1]))
+		initAllocs, _ = strconv.Atoi(string(m[2]))
+	}
+	b.ReportMetric(float64(fi.Size()), "binary-bytes")
+	b.ReportMetric(float64(tableBytes), "table-bytes")
+	b.ReportMetric(float64(initBytes), "init-bytes")
+	b.ReportMetric(float64(initAllocs), "init-allocs")
+}
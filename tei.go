@@ -0,0 +1,225 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlEscaper escapes text and attribute values of XML
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// teiLine is a paragraph, a heading or a page break with rend of enclosing blocks
+type teiLine struct {
+	block Block
+	rend  string
+}
+
+// teiGlyph is a gaiji declared in charDecl
+type teiGlyph struct {
+	id      string
+	desc    string
+	code    JISCode
+	unicode string
+}
+
+// teiWriter is the state of WriteTEI
+type teiWriter struct {
+	glyphs   []teiGlyph
+	declared map[string]bool   // ids of glyphs
+	unknown  map[string]string // annotation of gaiji without code or character to id of glyph
+}
+
+// WriteTEI writes doc as TEI P5 XML. The title, the author and the colophon (`底本：` and
+// the following paragraphs) are in teiHeader, headings start `<div>`s, gaiji are `<g>`
+// declared in charDecl with JIS X 0213 code and Unicode, and page breaks are `<pb/>`.
+func WriteTEI(w io.Writer, doc *Document) error {
+	body, colophon := teiSplitColophon(doc.Blocks)
+	t := &teiWriter{declared: map[string]bool{}, unknown: map[string]string{}}
+	var text strings.Builder
+	t.body(&text, teiFlatten(body, "", nil))
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<TEI xmlns=\"http://www.tei-c.org/ns/1.0\">\n")
+	b.WriteString("<teiHeader>\n<fileDesc>\n<titleStmt>\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", xmlEscaper.Replace(doc.Title))
+	if doc.Author != "" {
+		fmt.Fprintf(&b, "<author>%s</author>\n", xmlEscaper.Replace(doc.Author))
+	}
+	b.WriteString("</titleStmt>\n")
+	b.WriteString("<publicationStmt>\n<p>Converted from Aozora Bunko format</p>\n</publicationStmt>\n")
+	b.WriteString("<sourceDesc>\n")
+	if len(colophon) == 0 {
+		b.WriteString("<p>Aozora Bunko</p>\n")
+	}
+	for _, s := range colophon {
+		fmt.Fprintf(&b, "<p>%s</p>\n", xmlEscaper.Replace(s))
+	}
+	b.WriteString("</sourceDesc>\n</fileDesc>\n")
+	if len(t.glyphs) > 0 {
+		b.WriteString("<encodingDesc>\n<charDecl>\n")
+		for _, g := range t.glyphs {
+			fmt.Fprintf(&b, "<glyph xml:id=\"%s\">\n", g.id)
+			fmt.Fprintf(&b, "<desc>%s</desc>\n", xmlEscaper.Replace(g.desc))
+			if g.code.IsValid() {
+				fmt.Fprintf(&b, "<mapping type=\"JIS-X-0213\">%s</mapping>\n", g.code)
+			}
+			if g.unicode != "" {
+				fmt.Fprintf(&b, "<mapping type=\"Unicode\">%s</mapping>\n", xmlEscaper.Replace(g.unicode))
+			}
+			b.WriteString("</glyph>\n")
+		}
+		b.WriteString("</charDecl>\n</encodingDesc>\n")
+	}
+	b.WriteString("</teiHeader>\n<text>\n<body>\n")
+	b.WriteString(text.String())
+	b.WriteString("</body>\n</text>\n</TEI>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// teiSplitColophon splits blocks into the body and the colophon from `底本：`
+func teiSplitColophon(blocks []Block) ([]Block, []string) {
+	for i, bl := range blocks {
+		if bl.Kind != BlockParagraph || !strings.HasPrefix(PlainText(bl.Inlines), "底本：") {
+			continue
+		}
+		var colophon []string
+		for _, c := range blocks[i:] {
+			if s := PlainText(c.Inlines); c.Kind == BlockParagraph && s != "" {
+				colophon = append(colophon, s)
+			}
+		}
+		for i > 0 && blocks[i-1].Kind == BlockParagraph && len(blocks[i-1].Inlines) == 0 {
+			i--
+		}
+		return blocks[:i], colophon
+	}
+	return blocks, nil
+}
+
+// teiFlatten flattens 字下げ and 地付き blocks into rend of their lines
+func teiFlatten(blocks []Block, rend string, lines []teiLine) []teiLine {
+	for _, bl := range blocks {
+		switch bl.Kind {
+		case BlockIndent:
+			lines = teiFlatten(bl.Blocks, strings.TrimSpace(fmt.Sprintf("%s jisage-%d", rend, bl.Level)), lines)
+		case BlockBottom:
+			r := "chitsuki"
+			if bl.Level > 0 {
+				r = fmt.Sprintf("jiage-%d", bl.Level)
+			}
+			lines = teiFlatten(bl.Blocks, strings.TrimSpace(rend+" "+r), lines)
+		default:
+			lines = append(lines, teiLine{bl, rend})
+		}
+	}
+	return lines
+}
+
+func (t *teiWriter) body(b *strings.Builder, lines []teiLine) {
+	var divs []int // levels of open divs
+	for _, l := range lines {
+		rend := ""
+		if l.rend != "" {
+			rend = fmt.Sprintf(" rend=\"%s\"", l.rend)
+		}
+		switch l.block.Kind {
+		case BlockHeading:
+			for len(divs) > 0 && divs[len(divs)-1] >= l.block.Level {
+				b.WriteString("</div>\n")
+				divs = divs[:len(divs)-1]
+			}
+			fmt.Fprintf(b, "<div type=\"midashi\" n=\"%d\">\n<head%s>", l.block.Level, rend)
+			t.inlines(b, l.block.Inlines)
+			b.WriteString("</head>\n")
+			divs = append(divs, l.block.Level)
+		case BlockPageBreak:
+			b.WriteString("<pb/>\n")
+		default:
+			fmt.Fprintf(b, "<p%s>", rend)
+			t.inlines(b, l.block.Inlines)
+			b.WriteString("</p>\n")
+		}
+	}
+	for range divs {
+		b.WriteString("</div>\n")
+	}
+	if len(lines) == 0 {
+		b.WriteString("<p/>\n")
+	}
+}
+
+func (t *teiWriter) inlines(b *strings.Builder, inlines []Inline) {
+	for _, in := range inlines {
+		switch in.Kind {
+		case InlineText:
+			b.WriteString(xmlEscaper.Replace(in.Text))
+		case InlineRuby:
+			fmt.Fprintf(b, "<ruby><rb>%s</rb><rt>%s</rt></ruby>", xmlEscaper.Replace(in.Text), xmlEscaper.Replace(in.Ruby))
+		case InlineEmphasis:
+			fmt.Fprintf(b, "<hi rend=\"%s\">", teiRend(in.Style))
+			t.inlines(b, in.Children)
+			b.WriteString("</hi>")
+		case InlineTateChuYoko:
+			b.WriteString("<hi rend=\"tate-chu-yoko\">")
+			t.inlines(b, in.Children)
+			b.WriteString("</hi>")
+		case InlineGaiji:
+			fmt.Fprintf(b, "<g ref=\"#%s\">%s</g>", t.glyph(in), xmlEscaper.Replace(in.Text))
+		case InlineNote:
+			fmt.Fprintf(b, "<note type=\"annotation\">%s</note>", xmlEscaper.Replace(in.Annotation))
+		}
+	}
+}
+
+// glyph returns the id of glyph for gaiji in, declaring it at the first time.
+// Gaiji of the same code or character share a glyph, and gaiji of neither share
+// a glyph only with the same annotation.
+func (t *teiWriter) glyph(in Inline) string {
+	var id string
+	switch {
+	case in.Code.IsValid():
+		id = "jis-" + in.Code.String()
+	case in.Text != "":
+		var hex []string
+		for _, r := range in.Text {
+			hex = append(hex, fmt.Sprintf("%04X", r))
+		}
+		id = "u-" + strings.Join(hex, "-")
+	default:
+		if id, ok := t.unknown[in.Annotation]; ok {
+			return id
+		}
+		id = fmt.Sprintf("gaiji-%d", len(t.glyphs)+1)
+		t.unknown[in.Annotation] = id
+	}
+	if t.declared[id] {
+		return id
+	}
+	g := teiGlyph{id: id, desc: "※［＃" + in.Annotation + "］", unicode: in.Text}
+	if in.Code.IsValid() {
+		g.code = in.Code
+		if u, err := in.Code.Unicode(); err == nil {
+			g.unicode = u
+		}
+	}
+	t.declared[id] = true
+	t.glyphs = append(t.glyphs, g)
+	return id
+}
+
+// teiRend returns rend of the emphasis style
+func teiRend(style string) string {
+	switch style {
+	case "太字":
+		return "bold"
+	case "斜体":
+		return "italic"
+	}
+	if c := markdownEmClass(style); c != "" {
+		return c
+	}
+	return "emphasis"
+}
@@ -0,0 +1,82 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const teiSample = documentSample +
+	"※［＃「口＋世」、U+546D］と※［＃「さんずい＋不明」］、※［＃「てへん＋劣」、第3水準1-84-77］\n" +
+	"［＃中見出し］二［＃中見出し終わり］\n" +
+	"<&>\n" +
+	"\n\n\n" +
+	"底本：「吾輩は猫である」岩波文庫、岩波書店\n" +
+	"　　　1990（平成2）年4月16日第1刷発行\n"
+
+func TestWriteTEI(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTEI(&buf, ParseDocument(teiSample)); err != nil {
+		t.Fatalf("WriteTEI error: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"<title>吾輩は猫である</title>\n<author>夏目漱石</author>\n",
+		"<sourceDesc>\n<p>底本：「吾輩は猫である」岩波文庫、岩波書店</p>\n<p>　　　1990（平成2）年4月16日第1刷発行</p>\n</sourceDesc>\n",
+		"<glyph xml:id=\"jis-1-84-77\">\n<desc>※［＃「てへん＋劣」、第3水準1-84-77］</desc>\n<mapping type=\"JIS-X-0213\">1-84-77</mapping>\n<mapping type=\"Unicode\">挘</mapping>\n</glyph>\n",
+		"<glyph xml:id=\"u-546D\">\n",
+		"<glyph xml:id=\"gaiji-3\">\n<desc>※［＃「さんずい＋不明」］</desc>\n</glyph>\n",
+		"<div type=\"midashi\" n=\"1\">\n<head rend=\"jisage-3\">一</head>\n",
+		"<p>　<ruby><rb>吾輩</rb><rt>わがはい</rt></ruby>は<ruby><rb>猫である</rb><rt>ねこである</rt></ruby>。</p>\n",
+		"<p><hi rend=\"sesame\">じめじめ</hi>した所で<g ref=\"#jis-1-84-77\">挘</g>いた。</p>\n",
+		"<p rend=\"jisage-2\">明治<hi rend=\"tate-chu-yoko\">３８</hi>年</p>\n",
+		"<note type=\"annotation\">不明な注記</note>",
+		"<pb/>\n<p rend=\"chitsuki\">夏目</p>\n",
+		"<g ref=\"#u-546D\">呭</g>と<g ref=\"#gaiji-3\"></g>、<g ref=\"#jis-1-84-77\">挘</g>",
+		"<div type=\"midashi\" n=\"2\">\n<head>二</head>\n<p>&lt;&amp;&gt;</p>\n</div>\n</div>\n</body>\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteTEI got:\n%s\nwant containing: %q", got, want)
+		}
+	}
+	if strings.Count(got, "底本") != 1 {
+		t.Errorf("WriteTEI writes the colophon in the body")
+	}
+}
+
+func TestWriteTEIValid(t *testing.T) {
+	schema, err := loadRNG("testdata/tei_subset.rng")
+	if err != nil {
+		t.Fatalf("loadRNG error: %v", err)
+	}
+	for _, in := range []string{teiSample, documentSample, "題\n\n本文\n", "", "題\n\n［＃改ページ］\n",
+		"題\n\n※［＃「口＋世」、U+546D］※［＃「口＋世」、U+546D、12-3］※［＃「てへん＋劣」、第3水準1-84-77］※［＃「てへん＋劣」、1-84-77、5-6］\n"} {
+		var buf bytes.Buffer
+		if err := WriteTEI(&buf, ParseDocument(in)); err != nil {
+			t.Fatalf("WriteTEI error: %v", err)
+		}
+		if err := schema.validate(&buf); err != nil {
+			t.Errorf("WriteTEI(%q) is not valid: %v\n%s", in, err, buf.String())
+		}
+	}
+
+	invalid := []string{
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><text><body><p/></body></text></TEI>`,
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><div><p/></div><p/></body></text></TEI>`,
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><p><ruby><rt/></ruby></p></body></text></TEI>`,
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><p foo="1"/></body></text></TEI>`,
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><div type="a b"><p/></div></body></text></TEI>`,
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><p rend=""/></body></text></TEI>`,
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><p xml:id="1a"/></body></text></TEI>`,
+		`<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><p xml:id="p1"/><p xml:id="p1"/></body></text></TEI>`,
+	}
+	valid := `<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader><fileDesc><titleStmt><title/></titleStmt><publicationStmt><p/></publicationStmt><sourceDesc><p/></sourceDesc></fileDesc></teiHeader><text><body><div type="a"><p rend="a b" xml:id="p1"/></div></body></text></TEI>`
+	if err := schema.validate(strings.NewReader(valid)); err != nil {
+		t.Errorf("validate(%q) error: %v", valid, err)
+	}
+	for _, in := range invalid {
+		if err := schema.validate(strings.NewReader(in)); err == nil {
+			t.Errorf("validate(%q) got no error", in)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// This program looks up the tables of aozoraconv in both directions.
+// BenchmarkTableFootprint measures its binary and the package initialization.
+package main
+
+import (
+	"fmt"
+
+	"github.com/takahashim/aozoraconv"
+)
+
+func main() {
+	fmt.Println(aozoraconv.Uni2Jis("𠮟"))
+	fmt.Println(aozoraconv.Jis2Uni(1, 47, 52))
+}
@@ -0,0 +1,108 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// variants is the table of variant characters (異体字) not in JIS X 0208
+// and their fallback characters, in order of preference
+var variants = map[rune]string{
+	'鷗': "鴎",
+	'髙': "高",
+	'﨑': "崎",
+	'𠮷': "吉",
+	'德': "徳",
+	'栁': "柳",
+	'瀨': "瀬",
+	'槪': "概",
+	'卽': "即",
+	'淸': "清",
+	'靑': "青",
+	'鄕': "郷",
+	'頰': "頬",
+	'摑': "掴",
+	'蔣': "蒋",
+	'醬': "醤",
+	'麴': "麹",
+	'禱': "祷",
+	'俱': "倶",
+	'剝': "剥",
+	'𠮟': "叱",
+	'吞': "呑",
+	'噓': "嘘",
+	'姸': "妍",
+	'屛': "屏",
+	'幷': "并",
+	'瘦': "痩",
+	'繫': "繋",
+
+	// CJK compatibility ideographs
+	'\uFA10': "塚",
+	'\uFA12': "晴",
+	'\uFA17': "益",
+	'\uFA19': "神",
+	'\uFA1B': "福",
+	'\uFA26': "都",
+	'\uFA4A': "琢",
+}
+
+// SuggestFallback returns the fallback candidates in JIS X 0208 for r,
+// in order of preference. It returns nil if r is in JIS X 0208 or no candidate is known.
+func SuggestFallback(r rune) []rune {
+	if in0208(string(r)) {
+		return nil
+	}
+	var ret []rune
+	for _, c := range variants[r] {
+		if in0208(string(c)) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// in0208 checks chr is encodable in JIS X 0208
+func in0208(chr string) bool {
+	jis, err := Uni2Jis(chr)
+	return err == nil && Is0208(jis.Men(), jis.Ku(), jis.Ten())
+}
+
+// Fallback replaces characters not in JIS X 0208 with the best fallback candidates
+// followed by gaiji annotations of the original characters,
+// like `鴎※［＃「鴎」の異体字、第3水準1-94-69］`.
+// Annotations (`［＃…］`) are left as is.
+func Fallback(str string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range annotationRe.FindAllStringIndex(str, -1) {
+		b.WriteString(fallbackText(str[last:loc[0]]))
+		b.WriteString(str[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(fallbackText(str[last:]))
+	return b.String()
+}
+
+func fallbackText(str string) string {
+	var b strings.Builder
+	for _, r := range str {
+		c := SuggestFallback(r)
+		if len(c) == 0 {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteRune(c[0])
+		b.WriteString(fallbackAnnotation(r, c[0]))
+	}
+	return b.String()
+}
+
+// fallbackAnnotation returns gaiji annotation of r substituted with fallback
+func fallbackAnnotation(r, fallback rune) string {
+	desc := fmt.Sprintf("「%c」の異体字", fallback)
+	if jis, err := Uni2Jis(string(r)); err == nil {
+		return fmt.Sprintf("※［＃%s、%s］", desc, jis.Ref())
+	}
+	return fmt.Sprintf("※［＃%s、U+%04X］", desc, r)
+}
@@ -0,0 +1,72 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	for r, c := range variants {
+		if in0208(string(r)) {
+			t.Errorf("%c (%U) is in JIS X 0208", r, r)
+		}
+		for _, f := range c {
+			if !in0208(string(f)) {
+				t.Errorf("fallback %c (%U) of %c is not in JIS X 0208", f, f, r)
+			}
+		}
+	}
+}
+
+func TestSuggestFallback(t *testing.T) {
+	var convertedPairs = []struct {
+		in  rune
+		out string
+	}{
+		{'鷗', "鴎"},
+		{'髙', "高"},
+		{'﨑', "崎"},
+		{'𠮷', "吉"},
+		{'鴎', ""},
+		{'☺', ""},
+	}
+	for _, tt := range convertedPairs {
+		if got := string(SuggestFallback(tt.in)); got != tt.out {
+			t.Errorf("SuggestFallback(%c) got: %q want: %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	var convertedStrings = []struct {
+		in, out string
+	}{
+		{"森鷗外", "森鴎※［＃「鴎」の異体字、第3水準1-94-69］外"},
+		{"𠮷野家", "吉※［＃「吉」の異体字、U+20BB7］野家"},
+		{"髙﨑", "高※［＃「高」の異体字、U+9AD9］崎※［＃「崎」の異体字、第3水準1-47-82］"},
+		{"\uFA10", "塚※［＃「塚」の異体字、第3水準1-15-55］"},
+		{"［＃「鷗」は太字］", "［＃「鷗」は太字］"},
+		{"☺", "☺"},
+	}
+	for _, tt := range convertedStrings {
+		if got := Fallback(tt.in); got != tt.out {
+			t.Errorf("Fallback(%q) got: %q want: %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestEncodeWithFallback(t *testing.T) {
+	output := new(bytes.Buffer)
+	if err := Encode(strings.NewReader("森鷗外"), output); err == nil {
+		t.Errorf("Encode should be error without WithFallback")
+	}
+
+	output.Reset()
+	if err := Encode(strings.NewReader("森鷗外"), output, WithFallback()); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("森鴎※［＃「鴎」の異体字、第3水準1-94-69］外"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+}
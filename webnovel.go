@@ -0,0 +1,123 @@
+package aozoraconv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Dialect is a notation of web-novel platforms
+type Dialect int
+
+// Dialects of web-novel platforms
+const (
+	// DialectAozora is Aozora Bunko format itself (no conversion)
+	DialectAozora Dialect = iota
+	// DialectKakuyomu is the notation of カクヨム (`|漢字《かんじ》`, `《《傍点》》`)
+	DialectKakuyomu
+	// DialectNarou is the notation of 小説家になろう (`|漢字《かんじ》`, `漢字(かんじ)`)
+	DialectNarou
+	// DialectPixiv is the notation of pixiv (`[[rb:漢字 > かんじ]]`, `[newpage]`)
+	DialectPixiv
+)
+
+// String returns the name of the dialect
+func (d Dialect) String() string {
+	switch d {
+	case DialectKakuyomu:
+		return "kakuyomu"
+	case DialectNarou:
+		return "narou"
+	case DialectPixiv:
+		return "pixiv"
+	}
+	return "aozora"
+}
+
+// ParseDialect parses the name of the dialect ("kakuyomu", "narou" or "pixiv")
+func ParseDialect(s string) (Dialect, error) {
+	switch strings.ToLower(s) {
+	case "", "aozora":
+		return DialectAozora, nil
+	case "kakuyomu":
+		return DialectKakuyomu, nil
+	case "narou":
+		return DialectNarou, nil
+	case "pixiv":
+		return DialectPixiv, nil
+	}
+	return DialectAozora, fmt.Errorf("unknown dialect: %s", s)
+}
+
+var (
+	webLiteralRubyRe  = regexp.MustCompile(`[|｜]《([^《》\n]*)(》?)`)
+	webBarRubyRe      = regexp.MustCompile(`[|｜]([^|｜《》\n]+)《([^《》\n]+)》`)
+	webKakuyomuEmRe   = regexp.MustCompile(`《《([^《》\n]+)》》`)
+	webNarouParenRe   = regexp.MustCompile(`[|｜]([^|｜()（）\n]+)[(（]([^()（）\n]+)[)）]`)
+	webNarouKanaRe    = regexp.MustCompile(`(\p{Han}+)[(（]([\p{Hiragana}\p{Katakana}ー]{1,10})[)）]`)
+	webPixivRubyRe    = regexp.MustCompile(`\[\[rb:\s*([^>\]\n]+?)\s*>\s*([^\]\n]+?)\s*\]\]`)
+	webPixivNewpageRe = regexp.MustCompile(`\[newpage\]`)
+	webPixivChapterRe = regexp.MustCompile(`\[chapter:\s*([^\]\n]+?)\s*\]`)
+)
+
+// literalRuby and literalRubyEnd are the annotations of `《` and `》` in text,
+// not a start and an end of ruby
+const (
+	literalRuby    = "※［＃始め二重山括弧、1-1-52］"
+	literalRubyEnd = "※［＃終わり二重山括弧、1-1-53］"
+)
+
+// ImportWebNovel rewrites web-novel notations of the dialect into Aozora Bunko format:
+// ruby with full-width `｜`, emphasis dots (`・` ruby and `《《…》》`) into
+// `［＃「…」に傍点］`, and pixiv `[newpage]` into `［＃改ページ］`.
+func ImportWebNovel(str string, d Dialect) string {
+	switch d {
+	case DialectKakuyomu:
+		str = replaceLiteralRuby(str)
+		str = webKakuyomuEmRe.ReplaceAllStringFunc(str, func(s string) string {
+			return bouten(webKakuyomuEmRe.FindStringSubmatch(s)[1])
+		})
+		str = replaceBarRuby(str, webBarRubyRe)
+	case DialectNarou:
+		str = replaceLiteralRuby(str)
+		str = replaceBarRuby(str, webBarRubyRe)
+		str = replaceBarRuby(str, webNarouParenRe)
+		str = webNarouKanaRe.ReplaceAllString(str, "$1《$2》")
+	case DialectPixiv:
+		str = replaceBarRuby(str, webPixivRubyRe)
+		str = webPixivNewpageRe.ReplaceAllString(str, "［＃改ページ］")
+		str = webPixivChapterRe.ReplaceAllStringFunc(str, func(s string) string {
+			title := webPixivChapterRe.FindStringSubmatch(s)[1]
+			return title + "［＃「" + title + "」は中見出し］"
+		})
+	}
+	return str
+}
+
+// replaceLiteralRuby rewrites `|《…》` into text of `《…》` with both brackets annotated
+func replaceLiteralRuby(str string) string {
+	return webLiteralRubyRe.ReplaceAllStringFunc(str, func(s string) string {
+		m := webLiteralRubyRe.FindStringSubmatch(s)
+		if m[2] == "" {
+			return literalRuby + m[1]
+		}
+		return literalRuby + m[1] + literalRubyEnd
+	})
+}
+
+// replaceBarRuby rewrites ruby matched by re (base and reading) into `｜漢字《かんじ》`.
+// Ruby of only `・` is emphasis dots.
+func replaceBarRuby(str string, re *regexp.Regexp) string {
+	return re.ReplaceAllStringFunc(str, func(s string) string {
+		m := re.FindStringSubmatch(s)
+		if strings.Trim(m[2], "・﹅") == "" {
+			return bouten(m[1])
+		}
+		return "｜" + m[1] + "《" + m[2] + "》"
+	})
+}
+
+// bouten returns text with 傍点 annotation
+func bouten(text string) string {
+	return text + "［＃「" + text + "」に傍点］"
+}
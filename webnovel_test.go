@@ -0,0 +1,55 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestImportWebNovel(t *testing.T) {
+	var convertedStrings = []struct {
+		in      string
+		dialect Dialect
+		out     string
+	}{
+		{"|漢字《かんじ》と｜仮名《かな》", DialectKakuyomu, "｜漢字《かんじ》と｜仮名《かな》"},
+		{"これは《《傍点》》です", DialectKakuyomu, "これは傍点［＃「傍点」に傍点］です"},
+		{"|《括弧》", DialectKakuyomu, "※［＃始め二重山括弧、1-1-52］括弧※［＃終わり二重山括弧、1-1-53］"},
+		{"|《括弧", DialectKakuyomu, "※［＃始め二重山括弧、1-1-52］括弧"},
+		{"｜《括弧》と|漢字《かんじ》", DialectNarou, "※［＃始め二重山括弧、1-1-52］括弧※［＃終わり二重山括弧、1-1-53］と｜漢字《かんじ》"},
+		{"漢字《かんじ》", DialectKakuyomu, "漢字《かんじ》"},
+		{"|漢字(かんじ)と東京（とうきょう）", DialectNarou, "｜漢字《かんじ》と東京《とうきょう》"},
+		{"|強調《・・》", DialectNarou, "強調［＃「強調」に傍点］"},
+		{"これ(this)は", DialectNarou, "これ(this)は"},
+		{"[[rb:漢字 > かんじ]]", DialectPixiv, "｜漢字《かんじ》"},
+		{"一\n[newpage]\n二", DialectPixiv, "一\n［＃改ページ］\n二"},
+		{"[chapter:第一章]", DialectPixiv, "第一章［＃「第一章」は中見出し］"},
+		{"|漢字《かんじ》", DialectAozora, "|漢字《かんじ》"},
+	}
+	for _, tt := range convertedStrings {
+		if got := ImportWebNovel(tt.in, tt.dialect); got != tt.out {
+			t.Errorf("ImportWebNovel(%q, %v) got: %q want: %q", tt.in, tt.dialect, got, tt.out)
+		}
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	for _, d := range []Dialect{DialectAozora, DialectKakuyomu, DialectNarou, DialectPixiv} {
+		if got, err := ParseDialect(d.String()); err != nil || got != d {
+			t.Errorf("ParseDialect(%q) got: %v (%v)", d.String(), got, err)
+		}
+	}
+	if _, err := ParseDialect("unknown"); err == nil {
+		t.Errorf("ParseDialect should be error")
+	}
+}
+
+func TestEncodeWithDialect(t *testing.T) {
+	output := new(bytes.Buffer)
+	if err := Encode(strings.NewReader("[[rb:漢字 > かんじ]]"), output, WithDialect(DialectPixiv)); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if got, want := output.Bytes(), toSjis("｜漢字《かんじ》"); !bytes.Equal(got, want) {
+		t.Errorf("Encode got: %v want: %v", got, want)
+	}
+}
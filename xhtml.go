@@ -0,0 +1,332 @@
+package aozoraconv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+var (
+	xhtmlGaijiPathRe = regexp.MustCompile(`([12])-(\d{1,2})-(\d{1,2})\.png$`)
+	xhtmlGaijiAltRe  = regexp.MustCompile(`^※[（(](.*)[）)]$`)
+	xhtmlJisageRe    = regexp.MustCompile(`^jisage_(\d+)$`)
+	xhtmlChitsukiRe  = regexp.MustCompile(`^chitsuki_(\d+)$`)
+)
+
+// xhtmlBouten are the classes of `<em>` in Aozora Bunko XHTML and their annotations
+var xhtmlBouten = map[string]string{
+	"sesame_dot":                 "傍点",
+	"white_sesame_dot":           "白ゴマ傍点",
+	"black_circle":               "丸傍点",
+	"white_circle":               "白丸傍点",
+	"black_up-pointing_triangle": "黒三角傍点",
+	"white_up-pointing_triangle": "白三角傍点",
+	"bullseye":                   "二重丸傍点",
+	"fisheye":                    "蛇の目傍点",
+	"saltire":                    "ばつ傍点",
+	"underline_solid":            "傍線",
+	"underline_double":           "二重傍線",
+	"underline_dotted":           "鎖線",
+	"underline_dashed":           "破線",
+	"underline_wave":             "波線",
+}
+
+// xhtmlMidashi are the classes of headings and their annotations
+var xhtmlMidashi = map[string]string{
+	"o-midashi":    "大見出し",
+	"naka-midashi": "中見出し",
+	"ko-midashi":   "小見出し",
+}
+
+// htmlNode is an element or a text of XHTML
+type htmlNode struct {
+	name     string // element name; "" for text
+	attr     map[string]string
+	text     string
+	children []*htmlNode
+}
+
+// class checks the node has class c
+func (n *htmlNode) class(c string) bool {
+	for _, s := range strings.Fields(n.attr["class"]) {
+		if s == c {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the first element matching f in depth-first order
+func (n *htmlNode) find(f func(*htmlNode) bool) *htmlNode {
+	if n.name != "" && f(n) {
+		return n
+	}
+	for _, c := range n.children {
+		if found := c.find(f); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// parseXHTML parses XHTML into a tree of htmlNode
+func parseXHTML(r io.Reader) (*htmlNode, error) {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "shift_jis", "shift-jis", "sjis", "x-sjis", "windows-31j", "cp932":
+			return japanese.ShiftJIS.NewDecoder().Reader(input), nil
+		case "euc-jp":
+			return japanese.EUCJP.NewDecoder().Reader(input), nil
+		case "utf-8", "utf8":
+			return input, nil
+		}
+		return nil, fmt.Errorf("unsupported charset: %s", charset)
+	}
+
+	root := &htmlNode{name: "#document"}
+	stack := []*htmlNode{root}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &htmlNode{name: strings.ToLower(t.Name.Local), attr: map[string]string{}}
+			for _, a := range t.Attr {
+				n.attr[strings.ToLower(a.Name.Local)] = a.Value
+			}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			top.children = append(top.children, &htmlNode{text: string(t)})
+		}
+	}
+	return root, nil
+}
+
+// ImportXHTML converts an Aozora Bunko XHTML page into Aozora Bunko text format (Unicode).
+// Ruby, indentation, 傍点, headings and gaiji images are converted into annotations.
+// JIS X 0213 code of gaiji is recovered from the image path.
+func ImportXHTML(r io.Reader) (string, error) {
+	root, err := parseXHTML(r)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, c := range []string{"title", "subtitle", "original_title", "author", "translator", "editor"} {
+		if n := root.find(func(n *htmlNode) bool { return n.class(c) && n.name != "div" }); n != nil {
+			b.WriteString(strings.TrimSpace(xhtmlInline(n)))
+			b.WriteString("\n")
+		}
+	}
+	text := root.find(func(n *htmlNode) bool { return n.class("main_text") })
+	if text == nil {
+		text = root.find(func(n *htmlNode) bool { return n.name == "body" })
+	}
+	if text == nil {
+		return "", fmt.Errorf("no main text in XHTML")
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString(strings.TrimRight(xhtmlInline(text), "\n"))
+	b.WriteString("\n")
+	if n := root.find(func(n *htmlNode) bool { return n.class("bibliographical_information") }); n != nil {
+		b.WriteString("\n\n\n")
+		b.WriteString(strings.TrimSpace(xhtmlInline(n)))
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// xhtmlInline converts children of n
+func xhtmlInline(n *htmlNode) string {
+	return xhtmlInlineAfter(n, "")
+}
+
+// xhtmlInlineAfter converts children of n; prev is the text converted before n,
+// of which only the last character matters for ruby in n
+func xhtmlInlineAfter(n *htmlNode, prev string) string {
+	_, size := utf8.DecodeLastRuneInString(prev)
+	prev = prev[len(prev)-size:]
+	var b strings.Builder
+	b.WriteString(prev)
+	for _, c := range n.children {
+		b.WriteString(xhtmlNode(c, &b))
+	}
+	return b.String()[len(prev):]
+}
+
+// xhtmlNode converts n; prev is the text converted before n
+func xhtmlNode(n *htmlNode, prev *strings.Builder) string {
+	if n.name == "" {
+		// line breaks in XHTML source are not significant
+		return strings.NewReplacer("\r", "", "\n", "", "\u00A0", " ").Replace(n.text)
+	}
+	switch n.name {
+	case "br":
+		return "\n"
+	case "ruby":
+		return xhtmlRuby(n, prev.String())
+	case "img":
+		if n.class("gaiji") {
+			return xhtmlGaiji(n)
+		}
+		return ""
+	case "em":
+		for _, c := range strings.Fields(n.attr["class"]) {
+			if name, ok := xhtmlBouten[c]; ok {
+				text := xhtmlInlineAfter(n, prev.String())
+				return fmt.Sprintf("%s［＃「%s」に%s］", text, stripRuby(text), name)
+			}
+		}
+	case "h3", "h4", "h5":
+		for c, name := range xhtmlMidashi {
+			if n.class(c) {
+				text := xhtmlInlineAfter(n, prev.String())
+				return fmt.Sprintf("%s［＃「%s」は%s］", text, stripRuby(text), name)
+			}
+		}
+	case "div":
+		return xhtmlDiv(n, prev.String())
+	}
+	return xhtmlInlineAfter(n, prev.String())
+}
+
+// xhtmlRuby converts `<ruby><rb>漢字</rb><rp>（</rp><rt>かんじ</rt><rp>）</rp></ruby>`
+func xhtmlRuby(n *htmlNode, prev string) string {
+	var rb, rt string
+	for _, c := range n.children {
+		switch c.name {
+		case "rb":
+			rb += xhtmlInline(c)
+		case "rt":
+			rt += xhtmlInline(c)
+		case "rp":
+		default:
+			rb += xhtmlNode(c, &strings.Builder{})
+		}
+	}
+	if needsRubyBar(rb, prev) {
+		return "｜" + rb + "《" + rt + "》"
+	}
+	return rb + "《" + rt + "》"
+}
+
+// needsRubyBar checks ruby base needs `｜`: the base is not all kanji,
+// or the preceding text ends with kanji
+func needsRubyBar(base, prev string) bool {
+	for _, r := range base {
+		if !isRubyKanji(r) {
+			return true
+		}
+	}
+	if base == "" || strings.HasSuffix(prev, "］") {
+		return true
+	}
+	r := []rune(prev)
+	return len(r) > 0 && isRubyKanji(r[len(r)-1])
+}
+
+// isRubyKanji checks r is a character which can be a ruby base without `｜`
+func isRubyKanji(r rune) bool {
+	return unicode.Is(unicode.Han, r) || strings.ContainsRune("々仝〆〇ヶ", r)
+}
+
+// xhtmlGaiji converts a gaiji image into gaiji annotation
+func xhtmlGaiji(n *htmlNode) string {
+	desc := ""
+	if m := xhtmlGaijiAltRe.FindStringSubmatch(n.attr["alt"]); m != nil {
+		if g, err := ParseGaiji("※［＃" + m[1] + "］"); err == nil && g.Description != "" {
+			desc = "「" + g.Description + "」"
+		} else {
+			desc = m[1]
+		}
+	}
+	m := xhtmlGaijiPathRe.FindStringSubmatch(n.attr["src"])
+	if m == nil {
+		if desc == "" {
+			desc = n.attr["alt"]
+		}
+		return "※［＃" + desc + "］"
+	}
+	men, _ := strconv.Atoi(m[1])
+	ku, _ := strconv.Atoi(m[2])
+	ten, _ := strconv.Atoi(m[3])
+	if _, err := Jis2Uni(men, ku, ten); err != nil {
+		return "※［＃" + desc + "］"
+	}
+	if desc == "" {
+		// the character itself can not be a description because it is not in Shift_JIS
+		desc = "外字"
+	}
+	return fmt.Sprintf("※［＃%s、%s］", desc, JisRef(men, ku, ten))
+}
+
+// xhtmlDiv converts indentation blocks
+func xhtmlDiv(n *htmlNode, prev string) string {
+	start, end, single := "", "", ""
+	for _, c := range strings.Fields(n.attr["class"]) {
+		if m := xhtmlJisageRe.FindStringSubmatch(c); m != nil {
+			start = "［＃ここから" + toFullwidthDigits(m[1]) + "字下げ］"
+			end = "［＃ここで字下げ終わり］"
+			single = "［＃" + toFullwidthDigits(m[1]) + "字下げ］"
+		} else if m := xhtmlChitsukiRe.FindStringSubmatch(c); m != nil {
+			if m[1] == "0" {
+				start, end, single = "［＃ここから地付き］", "［＃ここで地付き終わり］", "［＃地付き］"
+			} else {
+				d := toFullwidthDigits(m[1])
+				start, end, single = "［＃ここから地から"+d+"字上げ］", "［＃ここで字上げ終わり］", "［＃地から"+d+"字上げ］"
+			}
+		}
+	}
+	if start == "" {
+		return xhtmlInlineAfter(n, prev)
+	}
+	body := xhtmlInline(n)
+	var lead string
+	if prev != "" && !strings.HasSuffix(prev, "\n") {
+		lead = "\n"
+	}
+	text := strings.TrimSuffix(body, "\n")
+	if !strings.Contains(text, "\n") {
+		return lead + single + text + "\n"
+	}
+	return lead + start + "\n" + text + "\n" + end + "\n"
+}
+
+// stripRuby removes ruby from str, to make the target of annotations
+func stripRuby(str string) string {
+	str = rubyRe.ReplaceAllString(str, "")
+	return strings.Replace(str, "｜", "", -1)
+}
+
+// toFullwidthDigits converts ASCII digits into full-width ones
+func toFullwidthDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if '0' <= r && r <= '9' {
+			return r - '0' + '０'
+		}
+		return r
+	}, s)
+}
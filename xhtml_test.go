@@ -0,0 +1,98 @@
+package aozoraconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const xhtmlSample = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN"
+    "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="ja" >
+<head>
+	<meta http-equiv="Content-Type" content="text/html;charset=UTF-8" />
+	<title>夏目漱石 吾輩は猫である</title>
+</head>
+<body>
+<div class="metadata">
+<h1 class="title">吾輩は猫である</h1>
+<h2 class="author">夏目漱石</h2>
+<br />
+<br />
+</div>
+<div id="contents" style="display:none"></div><div class="main_text"><br />
+<h3 class="o-midashi"><a class="midashi_anchor" id="midashi10">一</a></h3>
+<br />
+　吾輩は猫である。名前はまだ無い。<br />
+　どこで生れたかとんと<ruby><rb>見当</rb><rp>（</rp><rt>けんとう</rt><rp>）</rp></ruby>がつかぬ。<ruby><rb>何</rb><rp>（</rp><rt>なに</rt><rp>）</rp></ruby>でも薄暗い<em class="sesame_dot">じめじめ</em>した所で<img src="../../../gaiji/1-84/1-84-77.png" alt="※(「てへん＋劣」、第3水準1-84-77)" class="gaiji" />いた。<br />
+<div class="jisage_2" style="margin-left: 2em">二字下げの行<br /></div>
+<div class="jisage_1" style="margin-left: 1em">一行目<br />
+二行目<br />
+</div>
+<ruby><rb>ニャー</rb><rp>（</rp><rt>にゃー</rt><rp>）</rp></ruby>&nbsp;と<img src="../../../gaiji/2-01/2-01-01.png" class="gaiji" />。<br />
+</div>
+<div class="bibliographical_information">
+<hr />
+<br />
+底本：「吾輩は猫である」岩波文庫、岩波書店<br />
+</div>
+</body>
+</html>
+`
+
+func TestImportXHTML(t *testing.T) {
+	got, err := ImportXHTML(strings.NewReader(xhtmlSample))
+	if err != nil {
+		t.Fatalf("ImportXHTML error: %v", err)
+	}
+	want := "吾輩は猫である\n夏目漱石\n\n" +
+		"\n一［＃「一」は大見出し］\n" +
+		"　吾輩は猫である。名前はまだ無い。\n" +
+		"　どこで生れたかとんと見当《けんとう》がつかぬ。何《なに》でも薄暗いじめじめ［＃「じめじめ」に傍点］した所で※［＃「てへん＋劣」、第3水準1-84-77］いた。\n" +
+		"［＃２字下げ］二字下げの行\n" +
+		"［＃ここから１字下げ］\n一行目\n二行目\n［＃ここで字下げ終わり］\n" +
+		"｜ニャー《にゃー》 と※［＃外字、第4水準2-1-1］。\n" +
+		"\n\n\n底本：「吾輩は猫である」岩波文庫、岩波書店\n"
+	if got != want {
+		t.Errorf("ImportXHTML got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestImportXHTMLShiftJIS(t *testing.T) {
+	src := strings.Replace(xhtmlSample, `encoding="UTF-8"`, `encoding="Shift_JIS"`, 1)
+	got, err := ImportXHTML(bytes.NewReader(toSjis(src)))
+	if err != nil {
+		t.Fatalf("ImportXHTML error: %v", err)
+	}
+	if !strings.Contains(got, "見当《けんとう》") {
+		t.Errorf("ImportXHTML got: %q", got)
+	}
+
+	output := new(bytes.Buffer)
+	if err := Encode(strings.NewReader(strings.Replace(got, " ", "", -1)), output); err != nil {
+		t.Errorf("Encode error: %v", err)
+	}
+}
+
+func TestImportXHTMLNestedRuby(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want string
+	}{
+		{`東京<em class="sesame_dot"><ruby><rb>漢字</rb><rt>かんじ</rt></ruby></em>`, "東京｜漢字《かんじ》［＃「漢字」に傍点］\n"},
+		{`かな<em class="sesame_dot"><ruby><rb>漢字</rb><rt>かんじ</rt></ruby></em>`, "かな漢字《かんじ》［＃「漢字」に傍点］\n"},
+		{`東京<span class="notes"><ruby><rb>漢字</rb><rt>かんじ</rt></ruby></span>`, "東京｜漢字《かんじ》\n"},
+		{`東京<div><ruby><rb>漢字</rb><rt>かんじ</rt></ruby></div>`, "東京｜漢字《かんじ》\n"},
+	}
+	for _, tc := range testcases {
+		src := `<html xmlns="http://www.w3.org/1999/xhtml"><body><div class="main_text">` + tc.in + `</div></body></html>`
+		got, err := ImportXHTML(strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("ImportXHTML error: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("ImportXHTML(%q) got: %q want: %q", tc.in, got, tc.want)
+		}
+	}
+}